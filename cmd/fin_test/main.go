@@ -1,9 +1,12 @@
 package main
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/danmuck/dps_lib/logs"
+	"github.com/gin-gonic/gin"
+)
 
 func main() {
-	router := gin.Default()
-	router.Use(gin.Recovery(), gin.Logger())
+	router := gin.New()
+	router.Use(gin.Recovery(), logs.Middleware())
 
 }
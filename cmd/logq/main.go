@@ -0,0 +1,261 @@
+// logq reads files written by the logs package, in text, JSON or logfmt, and
+// prints the entries that match a query.
+//
+//	logq -level warn -service networks -since 1h server.log
+//	logq -grep 'timeout|refused' -format summary server.log server.log.1
+//	tail -f server.log | logq -format json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/danmuck/dps_lib/logs"
+)
+
+func main() {
+	level := flag.String("level", "debug", "minimum level")
+	service := flag.String("service", "", "only entries from this service tag, the caller's directory for text logs")
+	since := flag.String("since", "", "only entries at or after this time, RFC3339 or a duration ago like 30m")
+	until := flag.String("until", "", "only entries before this time, RFC3339 or a duration ago")
+	grep := flag.String("grep", "", "only entries whose message matches this regular expression")
+	format := flag.String("format", "table", "output format: table, json or summary")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logq [flags] [file ...]\n\nreads stdin when no file is given\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	q, re, err := buildQuery(*level, *service, *since, *until, *grep)
+	if err != nil {
+		fatal(err)
+	}
+	var out output
+	switch *format {
+	case "table":
+		out = newTable(os.Stdout)
+	case "json":
+		out = jsonOutput{os.Stdout}
+	case "summary":
+		out = newSummary(os.Stdout)
+	default:
+		fatal(fmt.Errorf("unknown format %q", *format))
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		if err := scan(name, q, re, out); err != nil {
+			fatal(err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func buildQuery(level, service, since, until, grep string) (logs.LogQuery, *regexp.Regexp, error) {
+	q := logs.LogQuery{Service: service}
+	var err error
+	if q.Level, err = logs.ParseLevel(level); err != nil {
+		return q, nil, err
+	}
+	if q.Since, err = parseTime(since); err != nil {
+		return q, nil, fmt.Errorf("-since: %w", err)
+	}
+	if q.Until, err = parseTime(until); err != nil {
+		return q, nil, fmt.Errorf("-until: %w", err)
+	}
+	var re *regexp.Regexp
+	if grep != "" {
+		if re, err = regexp.Compile(grep); err != nil {
+			return q, nil, fmt.Errorf("-grep: %w", err)
+		}
+	}
+	return q, re, nil
+}
+
+// parseTime accepts RFC3339, a bare date or a duration before now
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+// stderr receives the malformed line reports
+var stderr io.Writer = os.Stderr
+
+// scan feeds every matching entry of a file, or stdin for "-", to out.
+// malformed lines are reported on stderr and skipped, read errors end the scan.
+func scan(name string, q logs.LogQuery, re *regexp.Regexp, out output) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	p := logs.NewParser(r)
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var perr *logs.ParseError
+		if errors.As(err, &perr) {
+			fmt.Fprintf(stderr, "logq: %s: %v\n", name, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if !q.Match(e) || (re != nil && !re.MatchString(e.Message)) {
+			continue
+		}
+		if err := out.Write(e); err != nil {
+			return err
+		}
+	}
+}
+
+type output interface {
+	Write(e *logs.ServiceLog) error
+	Close() error
+}
+
+// table prints aligned columns, multi line messages keep their first line
+type table struct {
+	w *tabwriter.Writer
+}
+
+func newTable(w io.Writer) *table {
+	t := &table{w: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+	fmt.Fprintln(t.w, "TIME\tLEVEL\tSERVICE\tCALLER\tMESSAGE")
+	return t
+}
+
+func (t *table) Write(e *logs.ServiceLog) error {
+	ts, caller := "-", "-"
+	if !e.Time.IsZero() {
+		ts = e.Time.Format(time.DateTime)
+	}
+	if e.File != "" {
+		caller = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	msg, _, _ := strings.Cut(e.Message, "\n")
+	_, err := fmt.Fprintf(t.w, "%s\t%s\t%s\t%s\t%s\n", ts, e.Level, orDash(e.Service), caller, msg)
+	return err
+}
+
+func (t *table) Close() error {
+	return t.w.Flush()
+}
+
+// jsonOutput prints one JSON object per entry, as written by logs.JSONFormatter
+type jsonOutput struct {
+	w io.Writer
+}
+
+func (j jsonOutput) Write(e *logs.ServiceLog) error {
+	_, err := fmt.Fprintln(j.w, logs.JSONFormatter(e, ""))
+	return err
+}
+
+func (j jsonOutput) Close() error {
+	return nil
+}
+
+// summary counts entries per service and level
+type summary struct {
+	w           io.Writer
+	counts      map[string]map[logs.Level]int
+	total       int
+	first, last time.Time
+}
+
+func newSummary(w io.Writer) *summary {
+	return &summary{w: w, counts: make(map[string]map[logs.Level]int)}
+}
+
+func (s *summary) Write(e *logs.ServiceLog) error {
+	service := orDash(e.Service)
+	if s.counts[service] == nil {
+		s.counts[service] = make(map[logs.Level]int)
+	}
+	s.counts[service][e.Level]++
+	s.total++
+	if !e.Time.IsZero() {
+		if s.first.IsZero() || e.Time.Before(s.first) {
+			s.first = e.Time
+		}
+		if e.Time.After(s.last) {
+			s.last = e.Time
+		}
+	}
+	return nil
+}
+
+func (s *summary) Close() error {
+	levels := []logs.Level{logs.DEBUG, logs.INIT, logs.INFO, logs.WARN, logs.ERROR, logs.FATAL, logs.DEV}
+	services := make([]string, 0, len(s.counts))
+	for service := range s.counts {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	w := tabwriter.NewWriter(s.w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "SERVICE\t")
+	for _, l := range levels {
+		fmt.Fprintf(w, "%s\t", l)
+	}
+	fmt.Fprintln(w, "TOTAL\t")
+	for _, service := range services {
+		fmt.Fprintf(w, "%s\t", service)
+		sum := 0
+		for _, l := range levels {
+			fmt.Fprintf(w, "%d\t", s.counts[service][l])
+			sum += s.counts[service][l]
+		}
+		fmt.Fprintf(w, "%d\t\n", sum)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !s.first.IsZero() {
+		fmt.Fprintf(s.w, "\n%d entries from %s to %s\n", s.total, s.first.Format(time.DateTime), s.last.Format(time.DateTime))
+	} else {
+		fmt.Fprintf(s.w, "\n%d entries\n", s.total)
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func fatal(err error) {
+	fmt.Fprintf(stderr, "logq: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danmuck/dps_lib/logs"
+)
+
+// collect records the messages it is given
+type collect struct {
+	msgs []string
+}
+
+func (c *collect) Write(e *logs.ServiceLog) error {
+	c.msgs = append(c.msgs, e.Message)
+	return nil
+}
+
+func (c *collect) Close() error {
+	return nil
+}
+
+func TestBuildQuery(t *testing.T) {
+	q, re, err := buildQuery("warn", "networks", "30m", "2024-01-02", "timeout|refused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Level != logs.WARN || q.Service != "networks" {
+		t.Errorf("query = %+v", q)
+	}
+	if ago := time.Since(q.Since); ago < 29*time.Minute || ago > 31*time.Minute {
+		t.Errorf("since = %v, want 30m ago", q.Since)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local); !q.Until.Equal(want) {
+		t.Errorf("until = %v, want %v", q.Until, want)
+	}
+	if re == nil || !re.MatchString("connection refused") {
+		t.Errorf("grep = %v", re)
+	}
+	if _, re, err := buildQuery("debug", "", "", "", ""); err != nil || re != nil {
+		t.Errorf("empty query: %v, %v", re, err)
+	}
+
+	for _, c := range []struct {
+		level, since, until, grep string
+		want                      string
+	}{
+		{level: "loud", want: "loud"},
+		{level: "info", since: "yesterday", want: "-since"},
+		{level: "info", until: "soon", want: "-until"},
+		{level: "info", grep: "(", want: "-grep"},
+	} {
+		if _, _, err := buildQuery(c.level, "", c.since, c.until, c.grep); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("buildQuery(%q, %q, %q, %q) = %v, want an error about %s", c.level, c.since, c.until, c.grep, err, c.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	var warnings bytes.Buffer
+	prev := stderr
+	defer func() { stderr = prev }()
+	stderr = &warnings
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "server.log")
+	lines := strings.Join([]string{
+		`{"level":"WARN","service":"networks","msg":"read failed"}`,
+		`{"level":"INFO","service":"networks","msg":"read failed quietly"}`,
+		`{"level":`,
+		`{"level":"ERROR","service":"metrics","msg":"read failed"}`,
+		`{"level":"ERROR","service":"networks","msg":"no interfaces"}`,
+	}, "\n")
+	if err := os.WriteFile(name, []byte(lines+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	q, re, err := buildQuery("warn", "networks", "", "", "fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out collect
+	if err := scan(name, q, re, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"read failed"}; !slices.Equal(out.msgs, want) {
+		t.Errorf("matched %q, want %q", out.msgs, want)
+	}
+	if !strings.Contains(warnings.String(), "server.log: line 3:") {
+		t.Errorf("malformed line not reported: %q", warnings.String())
+	}
+
+	// read errors end the scan instead of being reported line after line
+	long := filepath.Join(dir, "long.log")
+	if err := os.WriteFile(long, bytes.Repeat([]byte("x"), 2<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		want error
+	}{
+		{dir, nil},
+		{long, bufio.ErrTooLong},
+		{filepath.Join(dir, "missing.log"), os.ErrNotExist},
+	} {
+		warnings.Reset()
+		err := scan(c.name, logs.LogQuery{}, nil, &collect{})
+		if err == nil || (c.want != nil && !errors.Is(err, c.want)) {
+			t.Errorf("scan(%s) = %v, want %v", c.name, err, c.want)
+		}
+		if warnings.Len() != 0 {
+			t.Errorf("scan(%s) reported %q", c.name, warnings.String())
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaults used for zero AlertOptions fields
+const (
+	DefaultAlertBatchSize = 20
+	DefaultAlertBatchWait = 5 * time.Second
+	DefaultAlertCooldown  = time.Minute
+	DefaultAlertRetries   = 3
+	DefaultAlertBackoff   = time.Second
+	DefaultAlertPending   = 1000
+)
+
+// AlertOptions configures an AlertSink
+type AlertOptions struct {
+	URL        string
+	Level      Level             // minimum level, ERROR when zero
+	BatchSize  int               // post as soon as this many entries are pending
+	BatchWait  time.Duration     // post pending entries at most this long after they arrive
+	Cooldown   time.Duration     // minimum time between two posts, entries wait for the next one
+	MaxRetries int               // retries of a failed post, negative disables retries
+	Backoff    time.Duration     // delay before the first retry, doubled for every further one
+	MaxPending int               // entries kept while waiting, the oldest are dropped beyond it
+	Headers    map[string]string // e.g. an Authorization header expected by the webhook
+	Client     *http.Client
+}
+
+// AlertPayload is the JSON body posted to the webhook. entries are formatted
+// by JSONFormatter.
+type AlertPayload struct {
+	Source  string            `json:"source"`  // host name of the process
+	Count   int               `json:"count"`   // entries in this post
+	Dropped int               `json:"dropped"` // entries lost since the last post
+	Entries []json.RawMessage `json:"entries"`
+}
+
+// AlertSink batches ERROR and FATAL entries and posts them to a webhook, so
+// failures reach someone without reading stdout:
+//
+//	alerts := logs.NewAlertSink(logs.AlertOptions{URL: os.Getenv("ALERT_WEBHOOK")})
+//	logs.AddSink(alerts)
+//	logs.OnShutdown("alerts", func(ctx context.Context) error { return alerts.Close() })
+//
+// posts happen on a background goroutine. Flush, which Fatal runs before
+// exiting, posts right away and skips the cooldown.
+type AlertSink struct {
+	opts   AlertOptions
+	source string
+
+	mu       sync.Mutex
+	pending  []json.RawMessage
+	dropped  int
+	lastPost time.Time
+
+	send chan struct{} // wakes the sender when a batch is full
+	stop chan struct{}
+	done chan struct{}
+	post sync.Mutex // serializes posts of the sender and Flush
+}
+
+func NewAlertSink(opts AlertOptions) *AlertSink {
+	if opts.Level == DEBUG {
+		opts.Level = ERROR
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultAlertBatchSize
+	}
+	if opts.BatchWait <= 0 {
+		opts.BatchWait = DefaultAlertBatchWait
+	}
+	if opts.Cooldown < 0 {
+		opts.Cooldown = 0
+	} else if opts.Cooldown == 0 {
+		opts.Cooldown = DefaultAlertCooldown
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultAlertRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultAlertBackoff
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = DefaultAlertPending
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	source, _ := os.Hostname()
+	s := &AlertSink{
+		opts:   opts,
+		source: source,
+		send:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Enabled accepts the configured level up to FATAL, DEV messages never alert
+func (s *AlertSink) Enabled(l Level) bool {
+	return l >= s.opts.Level && l <= FATAL
+}
+
+func (s *AlertSink) Log(e *ServiceLog) error {
+	entry := json.RawMessage(JSONFormatter(e, ""))
+	s.mu.Lock()
+	if len(s.pending) >= s.opts.MaxPending {
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, entry)
+	full := len(s.pending) >= s.opts.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.send <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush posts the pending entries now, ignoring the cooldown. retries are
+// bounded by the shutdown timeout so Fatal never hangs on a dead webhook.
+func (s *AlertSink) Flush() error {
+	shutdown.mu.Lock()
+	timeout := shutdown.timeout
+	shutdown.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.postPending(ctx, true)
+}
+
+// Close stops the sender and posts what is left
+func (s *AlertSink) Close() error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+	return s.Flush()
+}
+
+// run posts full batches and anything older than BatchWait, waiting out the
+// cooldown between posts
+func (s *AlertSink) run() {
+	defer close(s.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel() // interrupts retries
+	}()
+
+	timer := time.NewTimer(s.opts.BatchWait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.send:
+		case <-timer.C:
+		}
+		wait := s.opts.BatchWait
+		if left := s.cooldownLeft(); left > 0 {
+			wait = left
+		} else if err := s.postPending(ctx, false); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: alert sink: %v\n", err)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (s *AlertSink) cooldownLeft() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastPost.IsZero() {
+		return 0
+	}
+	return s.opts.Cooldown - time.Since(s.lastPost)
+}
+
+// postPending takes the pending entries and posts them with retries. entries
+// of a post that keeps failing are counted as dropped in the next payload.
+func (s *AlertSink) postPending(ctx context.Context, force bool) error {
+	s.post.Lock()
+	defer s.post.Unlock()
+
+	s.mu.Lock()
+	if len(s.pending) == 0 || (!force && !s.lastPost.IsZero() && time.Since(s.lastPost) < s.opts.Cooldown) {
+		s.mu.Unlock()
+		return nil
+	}
+	payload := AlertPayload{Source: s.source, Count: len(s.pending), Dropped: s.dropped, Entries: s.pending}
+	s.pending, s.dropped = nil, 0
+	s.lastPost = time.Now()
+	s.mu.Unlock()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := s.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.deliver(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			s.requeue(payload)
+			return ctx.Err()
+		}
+		if !retry || attempt >= s.opts.MaxRetries {
+			s.mu.Lock()
+			s.dropped += payload.Dropped + payload.Count
+			s.mu.Unlock()
+			return fmt.Errorf("post %s: %w", s.opts.URL, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			s.requeue(payload)
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// requeue puts the entries of an interrupted post back in front of the pending ones
+func (s *AlertSink) requeue(p AlertPayload) {
+	s.mu.Lock()
+	s.pending = append(p.Entries, s.pending...)
+	if n := len(s.pending) - s.opts.MaxPending; n > 0 {
+		s.pending = s.pending[n:]
+		s.dropped += n
+	}
+	s.dropped += p.Dropped
+	s.mu.Unlock()
+}
+
+// deliver makes one POST, retry reports whether a failure may be temporary
+func (s *AlertSink) deliver(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %s", resp.Status)
+	}
+	return false, fmt.Errorf("status %s", resp.Status)
+}
@@ -0,0 +1,201 @@
+package logs
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize is the queue length used when AsyncOptions.QueueSize is 0
+const DefaultQueueSize = 1024
+
+// DropPolicy decides what happens when an entry arrives at a full async queue
+type DropPolicy int
+
+const (
+	Block      DropPolicy = iota // wait for room, logging slows down to the speed of the sinks
+	DropDebug                    // drop DEBUG entries while the queue is full, block for the rest
+	DropOldest                   // discard the oldest queued entry to make room
+)
+
+// AsyncOptions configures asynchronous sink writes
+type AsyncOptions struct {
+	QueueSize int
+	Policy    DropPolicy
+}
+
+// async queue state. senders registers every enqueue under mu so StopAsync
+// can wait for them before closing the queue, the send itself happens without
+// holding mu.
+var async = struct {
+	mu      sync.RWMutex
+	queue   chan *ServiceLog
+	policy  DropPolicy
+	done    chan struct{}
+	senders *sync.WaitGroup
+	drainer atomic.Int64 // goroutine id of drainQueue, 0 when it is not running
+
+	pending struct {
+		sync.Mutex
+		cond *sync.Cond
+		n    int // entries queued or being written
+	}
+	dropped atomic.Uint64
+}{}
+
+func init() {
+	async.pending.cond = sync.NewCond(&async.pending)
+}
+
+// StartAsync moves sink writes onto a background goroutine fed by a bounded
+// queue, so hot paths only pay for formatting the message. the service buffers
+// and subscribers are still updated synchronously. a running queue is drained
+// and replaced.
+//
+//	logs.StartAsync(logs.AsyncOptions{QueueSize: 4096, Policy: logs.DropDebug})
+//	defer logs.Flush()
+func StartAsync(opts AsyncOptions) {
+	StopAsync()
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	async.mu.Lock()
+	async.queue = make(chan *ServiceLog, opts.QueueSize)
+	async.policy = opts.Policy
+	async.done = make(chan struct{})
+	async.senders = &sync.WaitGroup{}
+	go drainQueue(async.queue, async.done)
+	async.mu.Unlock()
+}
+
+// StopAsync writes out the queued entries and returns to synchronous writes
+func StopAsync() {
+	async.mu.Lock()
+	queue, done, senders := async.queue, async.done, async.senders
+	async.queue, async.done, async.senders = nil, nil, nil
+	async.mu.Unlock()
+	if queue == nil {
+		return
+	}
+	// senders waiting on a full queue get through while it drains
+	senders.Wait()
+	close(queue)
+	<-done
+}
+
+// Dropped returns the number of entries discarded by the drop policy
+func Dropped() uint64 {
+	return async.dropped.Load()
+}
+
+// Flush reports repeats held back by rate limits, waits until every queued
+// entry is written, then flushes the sinks that buffer output. Fatal and
+// Shutdown flush before the process exits.
+func Flush() error {
+	for _, e := range pendingSummaries() {
+		emit(e)
+	}
+	Drain()
+	return flushSinks()
+}
+
+// Drain waits until every queued entry is written, without the summaries and
+// sink flushes of Flush. a sink draining from inside Log returns right away,
+// the queue holds the entry it is writing.
+func Drain() {
+	if onDrainer() {
+		return
+	}
+	async.pending.Lock()
+	for async.pending.n > 0 {
+		async.pending.cond.Wait()
+	}
+	async.pending.Unlock()
+}
+
+// enqueue hands e to the async writer. it reports false when async mode is
+// off and the caller has to write e itself.
+func enqueue(e *ServiceLog) bool {
+	async.mu.RLock()
+	queue, policy, senders := async.queue, async.policy, async.senders
+	if queue != nil {
+		senders.Add(1)
+	}
+	async.mu.RUnlock()
+	if queue == nil {
+		return false
+	}
+	defer senders.Done()
+
+	addPending(1)
+	select {
+	case queue <- e:
+		return true
+	default:
+	}
+	// the queue is full
+	switch {
+	case policy == DropOldest:
+		for {
+			select {
+			case queue <- e:
+				return true
+			default:
+			}
+			select {
+			case <-queue:
+				async.dropped.Add(1)
+				addPending(-1)
+			default:
+			}
+		}
+	case policy == DropDebug && e.Level == DEBUG:
+		async.dropped.Add(1)
+		addPending(-1)
+	case onDrainer():
+		// a sink logging from the writer would wait for itself, write directly
+		addPending(-1)
+		return false
+	default:
+		queue <- e
+	}
+	return true
+}
+
+func drainQueue(queue <-chan *ServiceLog, done chan<- struct{}) {
+	async.drainer.Store(goid())
+	defer close(done)
+	defer async.drainer.Store(0)
+	for e := range queue {
+		writeSinks(e)
+		addPending(-1)
+	}
+}
+
+// onDrainer reports whether the caller runs on the async writer, e.g. a sink
+// that logs or flushes from inside Log
+func onDrainer() bool {
+	id := async.drainer.Load()
+	return id != 0 && id == goid()
+}
+
+// goid returns the id of the calling goroutine from its stack header,
+// "goroutine 18 [running]:"
+func goid() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	field, _, _ := strings.Cut(strings.TrimPrefix(string(buf[:n]), "goroutine "), " ")
+	id, _ := strconv.ParseInt(field, 10, 64)
+	return id
+}
+
+func addPending(n int) {
+	async.pending.Lock()
+	async.pending.n += n
+	if async.pending.n == 0 {
+		async.pending.cond.Broadcast()
+	}
+	async.pending.Unlock()
+}
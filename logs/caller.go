@@ -0,0 +1,166 @@
+package logs
+
+import (
+	"cmp"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxStackDepth bounds the frames walked for helpers and stack traces
+const maxStackDepth = 32
+
+// packageName returns the last element of the package that owns a function,
+// e.g. "github.com/danmuck/dps_lib/networks.(*Frame).ComputeDeltas" -> "networks"
+func packageName(funcName string) string {
+	path := packagePath(funcName)
+	if idx := strings.LastIndex(path, "/"); idx != -1 {
+		return path[idx+1:]
+	}
+	return path
+}
+
+// packagePath returns the import path of the package that owns a function
+func packagePath(funcName string) string {
+	slash := strings.LastIndex(funcName, "/")
+	if slash == -1 {
+		slash = 0
+	}
+	if dot := strings.Index(funcName[slash:], "."); dot != -1 {
+		return funcName[:slash+dot]
+	}
+	return funcName
+}
+
+// serviceTag maps a package name to its service tag via LOGGER_service_map,
+// falling back to the package name itself
+func serviceTag(pkg string) string {
+	if tag, ok := LOGGER_service_map[pkg]; ok {
+		return tag
+	}
+	return pkg
+}
+
+// modules of the running binary from its build info, longest path first so
+// nested modules win over their parents
+var modules = sync.OnceValue(func() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	mods := []string{info.Main.Path}
+	for _, dep := range info.Deps {
+		mods = append(mods, dep.Path)
+	}
+	mods = slices.DeleteFunc(mods, func(m string) bool { return m == "" })
+	slices.SortFunc(mods, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return mods
+})
+
+// modulePath shortens a source file to its path inside the module that owns
+// funcName, keeping the module name for context:
+//
+//	/home/dan/go/src/dps_lib/networks/utils.go -> dps_lib/networks/utils.go
+//
+// files outside any known module keep their parent directory only.
+func modulePath(file, funcName string) string {
+	if file == "" {
+		return ""
+	}
+	base := filepath.Base(file)
+	pkg := packagePath(funcName)
+	for _, mod := range modules() {
+		if pkg != mod && !strings.HasPrefix(pkg, mod+"/") {
+			continue
+		}
+		name := path.Base(mod)
+		if isMajorVersion(name) {
+			name = path.Base(path.Dir(mod))
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(pkg, mod), "/")
+		return path.Join(name, rel, base)
+	}
+	return path.Join(filepath.Base(filepath.Dir(file)), base)
+}
+
+// isMajorVersion matches the /vN suffix of a module path
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	return strings.Trim(elem[1:], "0123456789") == ""
+}
+
+// functions marked with Helper, skipped when looking for the caller
+var (
+	helpers     sync.Map // function name -> struct{}
+	helperCount atomic.Int32
+)
+
+// Helper marks the calling function as a logging helper, like testing.T.Helper.
+// entries logged from inside it report the helper's caller instead:
+//
+//	func logQuote(q Quote) {
+//		logs.Helper()
+//		logs.Info("%s %.2f", q.Symbol, q.Price)
+//	}
+func Helper() {
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	if _, loaded := helpers.LoadOrStore(frame.Function, struct{}{}); !loaded {
+		helperCount.Add(1)
+	}
+}
+
+func hasHelpers() bool {
+	return helperCount.Load() > 0
+}
+
+// callerFrames resolves pcs into frames, dropping leading helper frames and
+// everything from the runtime and test harness below the program
+func callerFrames(pcs []uintptr) []runtime.Frame {
+	var frames []runtime.Frame
+	it := runtime.CallersFrames(pcs)
+	for {
+		frame, more := it.Next()
+		if frame.Function == "runtime.main" || frame.Function == "runtime.goexit" ||
+			frame.Function == "testing.tRunner" {
+			break
+		}
+		if _, ok := helpers.Load(frame.Function); !ok || len(frames) > 0 {
+			frames = append(frames, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// formatStack renders frames as a trimmed stack trace, one call per two lines:
+//
+//	networks.(*Frame).ComputeDeltas
+//		dps_lib/networks/utils.go:120
+func formatStack(frames []runtime.Frame) string {
+	var b strings.Builder
+	for i, f := range frames {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(path.Base(f.Function))
+		b.WriteString("\n\t")
+		b.WriteString(modulePath(f.File, f.Function))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorMode decides whether a sink wraps its lines in ANSI styles
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // color terminals only, honoring NO_COLOR and FORCE_COLOR
+	ColorAlways                  // always color
+	ColorNever                   // never color
+)
+
+// Theme maps levels to the ANSI styles used by colored sinks.
+// styles can be combined, e.g. StyleBold + StyleRed or StyleWhite + BgRed.
+type Theme map[Level]string
+
+// DefaultTheme is used by sinks without a theme
+var DefaultTheme = Theme{
+	DEBUG: StyleGreen,
+	INIT:  StyleBlack,
+	INFO:  StyleBlue,
+	WARN:  StyleYellow,
+	ERROR: StyleRed,
+	FATAL: StyleMagenta,
+	DEV:   StyleMagenta,
+}
+
+// Style returns the style for l, "" when the theme has none
+func (t Theme) Style(l Level) string {
+	if t == nil {
+		return DefaultTheme[l]
+	}
+	return t[l]
+}
+
+// ParseColorMode converts "auto", "always" or "never" into a ColorMode
+func ParseColorMode(s string) (ColorMode, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto", "":
+		return ColorAuto, true
+	case "always", "on", "true":
+		return ColorAlways, true
+	case "never", "off", "false":
+		return ColorNever, true
+	}
+	return ColorAuto, false
+}
+
+// useColor resolves a ColorMode for w. in auto mode FORCE_COLOR wins over
+// NO_COLOR, then dumb terminals and anything that is not a terminal are plain.
+func useColor(w io.Writer, mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok && force != "0" && force != "false" {
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a character device such as a tty
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
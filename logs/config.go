@@ -0,0 +1,284 @@
+package logs
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// environment variables read by LoadEnv
+const (
+	EnvLevel  = "DPS_LOG_LEVEL"  // e.g. "info" or "warn,metrics=debug,auth=warn"
+	EnvTrace  = "DPS_LOG_TRACE"  // any value accepted by strconv.ParseBool
+	EnvFormat = "DPS_LOG_FORMAT" // format of the Stdout sink: "text", "json" or "logfmt"
+	EnvColor  = "DPS_LOG_COLOR"  // color mode of the Stdout sink: "auto", "always" or "never"
+	EnvStack  = "DPS_LOG_STACK"  // any value accepted by strconv.ParseBool
+	EnvTime   = "DPS_LOG_TIME"   // timestamp format, e.g. "rfc3339nano,utc", "utc", "elapsed" or "off"
+)
+
+// Config holds the runtime logging configuration.
+// Level applies to every service that has no entry in Services.
+type Config struct {
+	Level      Level            `json:"level"`
+	Trace      bool             `json:"trace"`       // include caller file and line
+	Timestamp  bool             `json:"timestamp"`   // prefix entries with a timestamp
+	TimeFormat string           `json:"time_format"` // see SetTimeFormat, TimeStamp when empty
+	TimeUTC    bool             `json:"time_utc"`    // timestamps in UTC instead of local time
+	StackTrace bool             `json:"stack_trace"` // attach stack traces to ERROR and FATAL entries
+	Services   map[string]Level `json:"services"`    // per service tag overrides
+}
+
+// active configuration, guarded by settings.mu
+var settings = struct {
+	mu         sync.RWMutex
+	level      Level
+	trace      bool
+	stack      bool
+	timestamp  bool
+	timeFormat string
+	timeUTC    bool
+	services   map[string]Level
+}{
+	level:      DEBUG,
+	trace:      false,
+	timestamp:  LOGGER_enable_timestamp,
+	timeFormat: TimeStamp,
+	services:   make(map[string]Level),
+}
+
+func init() {
+	if err := LoadEnv(); err != nil {
+		Warn("%v", err)
+	}
+}
+
+// Configure replaces the active configuration
+func Configure(cfg Config) {
+	settings.mu.Lock()
+	defer settings.mu.Unlock()
+	settings.level = cfg.Level
+	settings.trace = cfg.Trace
+	settings.stack = cfg.StackTrace
+	settings.timeFormat, _ = timeFormatName(cmp.Or(cfg.TimeFormat, TimeStamp))
+	settings.timeUTC = cfg.TimeUTC
+	settings.services = make(map[string]Level, len(cfg.Services))
+	maps.Copy(settings.services, cfg.Services)
+	settings.timestamp = cfg.Timestamp
+}
+
+// CurrentConfig returns a copy of the active configuration
+func CurrentConfig() Config {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return Config{
+		Level:      settings.level,
+		Trace:      settings.trace,
+		Timestamp:  settings.timestamp,
+		StackTrace: settings.stack,
+		TimeFormat: settings.timeFormat,
+		TimeUTC:    settings.timeUTC,
+		Services:   maps.Clone(settings.services),
+	}
+}
+
+// LoadEnv applies the DPS_LOG_* variables that are set
+func LoadEnv() error {
+	if spec, ok := os.LookupEnv(EnvLevel); ok {
+		if err := SetLevels(spec); err != nil {
+			return fmt.Errorf("logs: %s: %w", EnvLevel, err)
+		}
+	}
+	if raw, ok := os.LookupEnv(EnvTrace); ok {
+		trace, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("logs: %s: %w", EnvTrace, err)
+		}
+		SetTrace(trace)
+	}
+	if raw, ok := os.LookupEnv(EnvStack); ok {
+		stack, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("logs: %s: %w", EnvStack, err)
+		}
+		SetStackTrace(stack)
+	}
+	if spec, ok := os.LookupEnv(EnvTime); ok {
+		format, utc, enabled, err := parseTimeSpec(spec)
+		if err == nil && enabled {
+			err = SetTimeFormat(format, utc)
+		}
+		if err != nil {
+			return fmt.Errorf("logs: %s: %w", EnvTime, err)
+		}
+		if !enabled {
+			SetTimestamp(false)
+		}
+	}
+	if name, ok := os.LookupEnv(EnvFormat); ok {
+		f, err := ParseFormatter(name)
+		if err != nil {
+			return fmt.Errorf("logs: %s: %w", EnvFormat, err)
+		}
+		Stdout.SetFormatter(f)
+	}
+	if raw, ok := os.LookupEnv(EnvColor); ok {
+		mode, valid := ParseColorMode(raw)
+		if !valid {
+			return fmt.Errorf("logs: %s: unknown color mode %q", EnvColor, raw)
+		}
+		Stdout.SetColor(mode)
+	}
+	return nil
+}
+
+// SetLevels parses a comma separated level spec and applies it.
+// A bare level sets the default, service=level pairs set per service overrides:
+//
+//	"warn,metrics=debug,auth=warn"
+func SetLevels(spec string) error {
+	def, services, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	settings.mu.Lock()
+	defer settings.mu.Unlock()
+	if def != nil {
+		settings.level = *def
+	}
+	maps.Copy(settings.services, services)
+	return nil
+}
+
+// ParseLevels parses a level spec as accepted by SetLevels.
+// def is nil when the spec has no bare level.
+func ParseLevels(spec string) (def *Level, services map[string]Level, err error) {
+	services = make(map[string]Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		service, name, found := strings.Cut(part, "=")
+		if !found {
+			l, err := ParseLevel(part)
+			if err != nil {
+				return nil, nil, err
+			}
+			def = &l
+			continue
+		}
+		service = strings.TrimSpace(service)
+		if service == "" {
+			return nil, nil, fmt.Errorf("logs: missing service in %q", part)
+		}
+		l, err := ParseLevel(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		services[service] = l
+	}
+	return def, services, nil
+}
+
+// SetLevel sets the default level for services without an override
+func SetLevel(l Level) {
+	settings.mu.Lock()
+	settings.level = l
+	settings.mu.Unlock()
+}
+
+// GetLevel returns the default level
+func GetLevel() Level {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.level
+}
+
+// SetServiceLevel overrides the level for a single service tag
+func SetServiceLevel(service string, l Level) {
+	settings.mu.Lock()
+	settings.services[service] = l
+	settings.mu.Unlock()
+}
+
+// ClearServiceLevel removes a service override so the default level applies again
+func ClearServiceLevel(service string) {
+	settings.mu.Lock()
+	delete(settings.services, service)
+	settings.mu.Unlock()
+}
+
+// ServiceLevel returns the level in effect for a service tag
+func ServiceLevel(service string) Level {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	if l, ok := settings.services[service]; ok {
+		return l
+	}
+	return settings.level
+}
+
+// SetTrace enables or disables caller file and line output
+func SetTrace(enabled bool) {
+	settings.mu.Lock()
+	settings.trace = enabled
+	settings.mu.Unlock()
+}
+
+// TraceEnabled reports whether caller file and line output is enabled
+func TraceEnabled() bool {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.trace
+}
+
+// SetTimestamp enables or disables timestamps in text output
+func SetTimestamp(enabled bool) {
+	settings.mu.Lock()
+	settings.timestamp = enabled
+	settings.mu.Unlock()
+}
+
+// TimestampEnabled reports whether text output starts with a timestamp
+func TimestampEnabled() bool {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.timestamp
+}
+
+// SetStackTrace enables or disables stack traces on ERROR and FATAL entries
+func SetStackTrace(enabled bool) {
+	settings.mu.Lock()
+	settings.stack = enabled
+	settings.mu.Unlock()
+}
+
+// StackTraceEnabled reports whether ERROR and FATAL entries carry a stack trace
+func StackTraceEnabled() bool {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.stack
+}
+
+// Enabled reports whether a message at level l from service would be emitted
+func Enabled(l Level, service string) bool {
+	min := ServiceLevel(service)
+	return min != INACTIVE && l >= min
+}
+
+// minLevel returns the most verbose level in effect for any service
+func minLevel() Level {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	min := settings.level
+	for _, l := range settings.services {
+		if l < min {
+			min = l
+		}
+	}
+	return min
+}
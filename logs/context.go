@@ -0,0 +1,38 @@
+package logs
+
+import "context"
+
+// key of the Logger in a context.Context
+type loggerCtxKey struct{}
+
+// NewContext returns a copy of ctx that carries l
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or a Logger without fields
+func FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return std
+	}
+	if l, ok := ctx.Value(loggerCtxKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
+
+// With returns the Logger carried by ctx with additional fields:
+//
+//	logs.With(ctx, "user", id).Info("portfolio updated")
+func With(ctx context.Context, kv ...any) *Logger {
+	return FromContext(ctx).With(kv...)
+}
+
+// WithContext attaches fields to the Logger carried by ctx so they follow the
+// call chain, e.g. the request ID set by Middleware plus a portfolio ID:
+//
+//	ctx = logs.WithContext(ctx, "portfolio", p.ID)
+//	logs.FromContext(ctx).Debug("recalculating net worth")
+func WithContext(ctx context.Context, kv ...any) context.Context {
+	return NewContext(ctx, With(ctx, kv...))
+}
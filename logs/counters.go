@@ -0,0 +1,86 @@
+package logs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogCounts is a snapshot of the entry counters. entries are counted once they
+// pass the filter rules, before rate limiting, so a burst of suppressed
+// repeats still shows up.
+type LogCounts struct {
+	Since    time.Time                    `json:"since"`    // start of the counting period
+	Total    uint64                       `json:"total"`    // every counted entry
+	Levels   map[string]uint64            `json:"levels"`   // by level name
+	Services map[string]map[string]uint64 `json:"services"` // by service tag, then level name
+	Dropped  uint64                       `json:"dropped"`  // entries discarded by the async queue
+}
+
+// entries per level of one service
+type levelCounts [INACTIVE]atomic.Uint64
+
+// one counting period, ResetCounts swaps in a new one
+type countPeriod struct {
+	since    time.Time
+	services sync.Map // service tag -> *levelCounts
+}
+
+var counters atomic.Pointer[countPeriod]
+
+// period returns the current counting period, starting the first one lazily
+// since entries can be logged by other init funcs before this file's
+func period() *countPeriod {
+	if p := counters.Load(); p != nil {
+		return p
+	}
+	counters.CompareAndSwap(nil, &countPeriod{since: time.Now()})
+	return counters.Load()
+}
+
+// Counts returns the entries counted since start up or the last ResetCounts:
+//
+//	c := logs.Counts()
+//	c.Services["auth"]["error"]
+func Counts() LogCounts {
+	p := period()
+	c := LogCounts{
+		Since:    p.since,
+		Levels:   make(map[string]uint64),
+		Services: make(map[string]map[string]uint64),
+		Dropped:  Dropped(),
+	}
+	p.services.Range(func(key, value any) bool {
+		byLevel := make(map[string]uint64)
+		counts := value.(*levelCounts)
+		for l := range counts {
+			if v := counts[l].Load(); v > 0 {
+				name := Level(l).String()
+				byLevel[name] = v
+				c.Levels[name] += v
+				c.Total += v
+			}
+		}
+		c.Services[key.(string)] = byLevel
+		return true
+	})
+	return c
+}
+
+// ResetCounts starts a new counting period, the dropped counter is kept
+func ResetCounts() {
+	counters.Store(&countPeriod{since: time.Now()})
+}
+
+// count adds e to the counters of its service and level
+func count(e *ServiceLog) {
+	if e.Level < 0 || e.Level >= INACTIVE {
+		return
+	}
+	p := period()
+	v, ok := p.services.Load(e.Service)
+	if !ok {
+		v, _ = p.services.LoadOrStore(e.Service, new(levelCounts))
+	}
+	v.(*levelCounts)[e.Level].Add(1)
+}
@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Field is a key/value pair attached to an entry.
+// structured formatters emit fields as top level keys.
+type Field struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// F is shorthand for building a Field
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger writes entries with a fixed set of fields.
+// the package level functions use a Logger without fields.
+type Logger struct {
+	fields  []Field
+	service string // overrides the service tag of the caller when set
+	skip    int    // extra frames between the caller and the logging method
+	sinks   []Sink // written in addition to the registered sinks
+}
+
+var std = &Logger{}
+
+// WithFields returns a Logger that attaches kv to every entry, see (*Logger).With
+func WithFields(kv ...any) *Logger {
+	return std.With(kv...)
+}
+
+// With returns a copy of l with additional fields.
+// kv is a list of alternating keys and values, Field values may be mixed in:
+//
+//	logs.WithFields("user", id, logs.F("portfolio", pid)).Info("updated")
+func (l *Logger) With(kv ...any) *Logger {
+	c := *l
+	c.fields = append(slices.Clip(l.fields), toFields(kv)...)
+	return &c
+}
+
+// WithService returns a copy of l that tags entries with service instead of the caller's package
+func (l *Logger) WithService(service string) *Logger {
+	c := *l
+	c.service = service
+	return &c
+}
+
+// WithCallerSkip returns a copy of l that reports the caller n frames further up,
+// for wrappers that always sit between the real caller and l. see also Helper.
+func (l *Logger) WithCallerSkip(n int) *Logger {
+	c := *l
+	c.skip += n
+	return &c
+}
+
+// WithSinks returns a copy of l whose entries are also written to s, after the
+// registered sinks and outside the async queue. loggers derived from it keep s,
+// which scopes output to one caller, e.g. a test capturing its own entries.
+func (l *Logger) WithSinks(s ...Sink) *Logger {
+	c := *l
+	c.sinks = append(slices.Clip(l.sinks), s...)
+	return &c
+}
+
+// Fields returns the fields attached to l
+func (l *Logger) Fields() []Field {
+	return slices.Clone(l.fields)
+}
+
+// Logf writes a message at an arbitrary level with that level's default tag,
+// unlike Fatal it never exits
+func (l *Logger) Logf(level Level, format string, v ...any) {
+	l.output(level, "", levelTags[level], format, v)
+}
+
+func (l *Logger) Log(format string, v ...any) {
+	storeServiceLog(format, l.output(INFO, StyleWhite, "[logs]", format, v))
+}
+
+func (l *Logger) Fatal(format string, v ...any) {
+	l.output(FATAL, "", "[fatal]", format, v)
+	exit(1)
+}
+
+func (l *Logger) Err(format string, v ...any) {
+	l.output(ERROR, "", "[error]", format, v)
+}
+
+func (l *Logger) Warn(format string, v ...any) {
+	l.output(WARN, "", "[warn]", format, v)
+}
+
+func (l *Logger) Info(format string, v ...any) {
+	l.output(INFO, "", "[info]", format, v)
+}
+
+func (l *Logger) Debug(format string, v ...any) {
+	l.output(DEBUG, "", "[debug]", format, v)
+}
+
+func (l *Logger) Dev(format string, v ...any) {
+	l.output(DEV, "", "[dev_]", format, v)
+}
+
+func (l *Logger) Init(format string, v ...any) {
+	l.output(INIT, "", "[init]", format, v)
+}
+
+// toFields pairs up alternating keys and values.
+// a trailing key without a value is kept under "!BADKEY" like log/slog does.
+func toFields(kv []any) []Field {
+	fields := make([]Field, 0, len(kv)/2)
+	for i := 0; i < len(kv); i++ {
+		if f, ok := kv[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		if i+1 == len(kv) {
+			fields = append(fields, Field{Key: "!BADKEY", Value: kv[i]})
+			break
+		}
+		fields = append(fields, Field{Key: fmt.Sprint(kv[i]), Value: kv[i+1]})
+		i++
+	}
+	return fields
+}
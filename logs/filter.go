@@ -0,0 +1,197 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// EnvFilter holds comma separated filter rules applied at init, e.g. "-networks:debug,+networks:debug~^Upload"
+const EnvFilter = "DPS_LOG_FILTER"
+
+// FilterRule includes or excludes entries. rules are written as
+//
+//	[+|-]service[:level][@package][~regex]
+//
+// service and level may be "*", package matches the caller's import path or
+// its trailing elements and regex is matched against the message. e.g.
+//
+//	-networks:debug                      mute networks debug output
+//	+metrics:*                           keep everything from metrics
+//	-*:debug@networks~^Upload            mute debug lines starting with "Upload" from the networks package
+//
+// a rule without a sign includes.
+type FilterRule struct {
+	Include bool
+	Service string         // "*" matches every service
+	Level   string         // level name or "*"
+	Package string         // "" matches every package
+	Pattern *regexp.Regexp // nil matches every message
+
+	raw   string
+	level Level
+}
+
+// active rules, replaced as a whole so logging never takes a lock
+var filterRules atomic.Pointer[[]FilterRule]
+
+func init() {
+	if err := SetFilter(LOGGER_filter...); err != nil {
+		Warn("LOGGER_filter: %v", err)
+	}
+	if raw, ok := os.LookupEnv(EnvFilter); ok {
+		if err := AddFilter(strings.Split(raw, ",")...); err != nil {
+			Warn("%s: %v", EnvFilter, err)
+		}
+	}
+}
+
+// ParseFilterRule parses a single rule as described on FilterRule
+func ParseFilterRule(s string) (FilterRule, error) {
+	raw := strings.TrimSpace(s)
+	r := FilterRule{Include: true, Service: "*", Level: "*", raw: raw}
+	rest := raw
+	switch {
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "-"):
+		r.Include = false
+		rest = rest[1:]
+	}
+	if before, expr, ok := strings.Cut(rest, "~"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return r, fmt.Errorf("logs: filter %q: %w", raw, err)
+		}
+		r.Pattern = re
+		rest = before
+	}
+	if before, pkg, ok := strings.Cut(rest, "@"); ok {
+		r.Package = pkg
+		rest = before
+	}
+	service, level, _ := strings.Cut(rest, ":")
+	if service != "" {
+		r.Service = service
+	}
+	if level != "" && level != "*" {
+		l, err := ParseLevel(level)
+		if err != nil {
+			return r, fmt.Errorf("logs: filter %q: %w", raw, err)
+		}
+		r.Level = l.String()
+		r.level = l
+	}
+	return r, nil
+}
+
+// Match reports whether the rule applies to e, regardless of its sign
+func (r FilterRule) Match(e *ServiceLog) bool {
+	if r.Service != "*" && r.Service != e.Service {
+		return false
+	}
+	if r.Level != "*" && r.level != e.Level {
+		return false
+	}
+	if r.Package != "" && !matchPackage(r.Package, e.Package) {
+		return false
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(e.Message) {
+		return false
+	}
+	return true
+}
+
+func (r FilterRule) String() string {
+	return r.raw
+}
+
+// matchPackage matches an import path exactly, by its trailing elements or as a glob
+func matchPackage(pattern, pkg string) bool {
+	if pattern == pkg || strings.HasSuffix(pkg, "/"+pattern) {
+		return true
+	}
+	ok, _ := path.Match(pattern, pkg)
+	return ok
+}
+
+// SetFilter replaces the active rules. nothing changes when a rule fails to parse.
+func SetFilter(rules ...string) error {
+	parsed, err := parseFilterRules(rules)
+	if err != nil {
+		return err
+	}
+	filterRules.Store(&parsed)
+	return nil
+}
+
+// AddFilter appends rules after the active ones, giving them precedence
+func AddFilter(rules ...string) error {
+	parsed, err := parseFilterRules(rules)
+	if err != nil {
+		return err
+	}
+	for {
+		current := filterRules.Load()
+		next := append(loadedRules(current), parsed...)
+		if filterRules.CompareAndSwap(current, &next) {
+			return nil
+		}
+	}
+}
+
+// ClearFilter removes every rule so all entries pass
+func ClearFilter() {
+	filterRules.Store(&[]FilterRule{})
+}
+
+// FilterRules returns the active rules in evaluation order
+func FilterRules() []string {
+	rules := loadedRules(filterRules.Load())
+	out := make([]string, len(rules))
+	for i, r := range rules {
+		out[i] = r.String()
+	}
+	return out
+}
+
+// FilterAllows evaluates the active rules against e. the last matching rule
+// decides, entries that match no rule are kept.
+func FilterAllows(e *ServiceLog) bool {
+	rules := filterRules.Load()
+	if rules == nil {
+		return true
+	}
+	for i := len(*rules) - 1; i >= 0; i-- {
+		if (*rules)[i].Match(e) {
+			return (*rules)[i].Include
+		}
+	}
+	return true
+}
+
+// loadedRules copies a stored rule list, which is nil before init
+func loadedRules(rules *[]FilterRule) []FilterRule {
+	if rules == nil {
+		return nil
+	}
+	return append([]FilterRule(nil), *rules...)
+}
+
+func parseFilterRules(rules []string) ([]FilterRule, error) {
+	parsed := make([]FilterRule, 0, len(rules))
+	for _, raw := range rules {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		r, err := ParseFilterRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
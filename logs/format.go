@@ -0,0 +1,161 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TextFormatter is the default human readable format.
+// with trace enabled the message is prefixed with its centered tag and caller,
+// with timestamps enabled by the time in the format set by SetTimeFormat:
+//
+//	Jan  2 15:04:05  [warn]  [dps_lib/networks/utils.go: 120] message key=value
+func TextFormatter(e *ServiceLog, style string) string {
+	line := e.Message
+	if len(e.Fields) > 0 {
+		line += " " + logfmtFields(e.Fields)
+	}
+	if TraceEnabled() {
+		path := FormatPath(e.File, 32)                         // max 32 chars
+		tag := CenterTag(e.Tag, 9)                             // padded 9 and centered tag
+		lineStr := fmt.Sprintf(":%4d", e.Line)                 // pad line number
+		prefix := fmt.Sprintf("%s[%s%s] ", tag, path, lineStr) // final prefix
+		line = prefix + line
+	}
+	if TimestampEnabled() {
+		line = FormatTime(e.Time) + " " + line
+	}
+	if e.Stack != "" {
+		line += "\n" + indentStack(e.Stack)
+	}
+	if style == "" {
+		return line
+	}
+	return ColorText(style, line)
+}
+
+// JSONFormatter emits one JSON object per entry.
+// fields become top level keys, a field that collides with a built in key is prefixed with "field.".
+// style is ignored.
+func JSONFormatter(e *ServiceLog, style string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range structuredFields(e) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(jsonValue(f.Value))
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// LogfmtFormatter emits one logfmt line per entry, style is ignored
+//
+//	time=2025-07-01T12:00:00Z level=warn service=networks caller=networks/utils.go:120 msg="message" key=value
+func LogfmtFormatter(e *ServiceLog, style string) string {
+	return logfmtFields(structuredFields(e))
+}
+
+// ParseFormatter returns the formatter registered under name: "text", "json" or "logfmt"
+func ParseFormatter(name string) (Formatter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "text":
+		return TextFormatter, nil
+	case "json":
+		return JSONFormatter, nil
+	case "logfmt":
+		return LogfmtFormatter, nil
+	}
+	return nil, fmt.Errorf("logs: unknown format %q", name)
+}
+
+// indentStack indents every line of a stack trace below the entry
+func indentStack(stack string) string {
+	return "\t" + strings.ReplaceAll(stack, "\n", "\n\t")
+}
+
+// structuredFields flattens an entry into the ordered keys used by JSON and logfmt output
+func structuredFields(e *ServiceLog) []Field {
+	fields := []Field{
+		{"time", structuredTime(e.Time)},
+		{"level", e.Level.String()},
+		{"service", e.Service},
+		{"caller", fmt.Sprintf("%s:%d", e.File, e.Line)},
+		{"msg", e.Message},
+	}
+	if e.Stack != "" {
+		fields = append(fields, Field{"stack", e.Stack})
+	}
+	reserved := len(fields)
+	for _, f := range e.Fields {
+		for _, r := range fields[:reserved] {
+			if r.Key == f.Key {
+				f.Key = "field." + f.Key
+				break
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// jsonValue marshals v, falling back to its string form for values json can't encode
+func jsonValue(v any) []byte {
+	if err, ok := v.(error); ok && !isNil(v) {
+		v = err.Error()
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return b
+	}
+	b, _ := json.Marshal(fmt.Sprint(v))
+	return b
+}
+
+// isNil reports whether v is nil or a nil pointer in an interface, calling
+// Error or String on those may panic
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// logfmtFields renders fields as space separated key=value pairs
+func logfmtFields(fields []Field) string {
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(logfmtValue(f.Key))
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(fmt.Sprint(f.Value)))
+	}
+	return b.String()
+}
+
+// logfmtValue quotes s when it is empty or contains spaces, quotes, '=' or control characters
+func logfmtValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
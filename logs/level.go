@@ -0,0 +1,89 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level orders log entries by severity. A message is emitted when its level
+// is greater than or equal to the active level for its service, so lower
+// levels are more verbose:
+//
+//	DEBUG < INIT < INFO < WARN < ERROR < FATAL < DEV < INACTIVE
+//
+// DEV sits above FATAL so developer messages survive every mode except
+// INACTIVE, which silences everything.
+type Level int32
+
+const (
+	DEBUG    Level = iota // Verbosity Development: everything
+	INIT                  // service registration and startup
+	INFO                  // general information
+	WARN                  // Verbosity Testing: warnings and above
+	ERROR                 // Verbosity Production: errors and above
+	FATAL                 // fatal errors, the process exits afterwards
+	DEV                   // developer scratch messages
+	INACTIVE              // Verbosity Min: nothing is emitted
+)
+
+var levelNames = map[Level]string{
+	DEBUG:    "debug",
+	INIT:     "init",
+	INFO:     "info",
+	WARN:     "warn",
+	ERROR:    "error",
+	FATAL:    "fatal",
+	DEV:      "dev",
+	INACTIVE: "inactive",
+}
+
+// default display tag per level, as used by the level functions
+var levelTags = map[Level]string{
+	DEBUG: "[debug]",
+	INIT:  "[init]",
+	INFO:  "[info]",
+	WARN:  "[warn]",
+	ERROR: "[error]",
+	FATAL: "[fatal]",
+	DEV:   "[dev_]",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel converts a level name such as "debug" or "WARN" into a Level.
+// "warning", "err" and "off" are accepted as aliases.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch name {
+	case "warning":
+		return WARN, nil
+	case "err":
+		return ERROR, nil
+	case "off", "none":
+		return INACTIVE, nil
+	}
+	for l, n := range levelNames {
+		if n == name {
+			return l, nil
+		}
+	}
+	return INACTIVE, fmt.Errorf("logs: unknown level %q", s)
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
@@ -2,23 +2,16 @@ package logs
 
 import (
 	"fmt"
-	"os"
 	"runtime"
 	"strings"
 	"time"
 )
 
-const (
-	INACTIVE = iota // Verbosity Min
-	DEBUG           // Verbosity Development
-	WARN            // Verbosity Testing
-	ERROR           // Verbosity Production
-)
-
-const MODE = DEBUG  // Set the logging mode, e.g., DEV, ERROR, WARN, DEBUG
-const TRACE = false // Enable/disable stack trace logging
+// levels, trace mode and per service overrides are set at runtime, see config.go
+// LOGGER_filter seeds the filter rules at init, change them at runtime with SetFilter
+// LOGGER_enable_timestamp seeds timestamps at init, change them with SetTimestamp
 var (
-	LOGGER_filter           = []string{"api:users"}
+	LOGGER_filter           = []string{}
 	LOGGER_enable_timestamp = false
 	LOGGER_service_map      = map[string]string{
 		"api":     "api",
@@ -38,99 +31,147 @@ func ColorTest() {
 	Info("This is an info message")
 
 	Debug("This is a debug message")
-	Fatal("This is a fatal message")
+	Logf(FATAL, "This is a fatal message") // styled like Fatal without exiting
 }
 
 func Log(format string, v ...any) {
-	log := &ServiceLog{
-		ts:  time.Now(),
-		msg: fmt.Sprintf(format, v...),
-	}
-	for key, value := range LOGGER_service_map {
-		if strings.Contains(format, value) {
-			logger.logs[key] = log
-		}
-	}
-	Print(StyleWhite, "[logs]", format, v...)
+	storeServiceLog(format, std.output(INFO, StyleWhite, "[logs]", format, v))
 }
 
-// @TODO -- i think this could be exploited
+// Fatal logs the message, runs the shutdown hooks, flushes the sinks and exits with
+// status 1 through the exit func, see OnShutdown and SetExitFunc
 func Fatal(format string, v ...any) {
-	Print(StyleMagenta, "[fatal]", format, v...)
-	if format == "This is a fatal message" {
-		return
-	}
-	os.Exit(1)
+	std.output(FATAL, "", "[fatal]", format, v)
+	exit(1)
 }
 
 func Err(format string, v ...any) {
-	if ERROR <= MODE {
-		return
-	}
-	Print(StyleRed, "[error]", format, v...)
+	std.output(ERROR, "", "[error]", format, v)
 }
 
 func Warn(format string, v ...any) {
-	if WARN <= MODE {
-		return
-	}
-	Print(StyleYellow, "[warn]", format, v...)
+	std.output(WARN, "", "[warn]", format, v)
 }
 
 func Info(format string, v ...any) {
-	if WARN <= MODE {
-		return
-	}
-	Print(StyleBlue, "[info]", format, v...)
+	std.output(INFO, "", "[info]", format, v)
 }
 
 func Debug(format string, v ...any) {
-	if DEBUG < MODE {
-		return
-	}
-	Print(StyleGreen, "[debug]", format, v...)
+	std.output(DEBUG, "", "[debug]", format, v)
 }
 
 func Dev(format string, v ...any) {
-
-	Print(StyleMagenta, "[dev_]", format, v...)
+	std.output(DEV, "", "[dev_]", format, v)
 }
 
 func Init(format string, v ...any) {
-	if DEBUG <= MODE {
+	std.output(INIT, "", "[init]", format, v)
+}
+
+// Logf writes a message at an arbitrary level, see (*Logger).Logf
+func Logf(level Level, format string, v ...any) {
+	std.output(level, "", levelTags[level], format, v)
+}
+
+// storeServiceLog also keeps a Log entry in the buffer of every service named in its format
+func storeServiceLog(format string, e *ServiceLog) {
+	if e == nil {
 		return
 	}
-	Print(StyleBlack, "[init]", format, v...)
+	for key, value := range LOGGER_service_map {
+		if key != e.Service && strings.Contains(format, value) {
+			logger.store(key, e)
+		}
+	}
 }
 
 // T is the type of log, e.g. "dev", "error", "warn", etc.
 // format, v... are the format string and values to Print
+// C is the style used by colored sinks in place of their theme and the level is
+// taken from T, defaulting to INFO
 func Print(C, T, format string, v ...any) {
-	if LOGGER_enable_timestamp {
-		fmt.Println(ColorText(C, String(C, T, format, v...)))
-	} else {
-		fmt.Println(ColorText(C, String(C, T, format, v...)))
+	std.output(tagLevel(T), C, T, format, v)
+}
+
+// String renders a message the way the default text sink would, without color
+func String(C, T, format string, v ...any) string {
+	e := newEntry(1, tagLevel(T), C, T)
+	e.Message = RedactString(fmt.Sprintf(format, v...))
+	return TextFormatter(e, "")
+}
+
+// output builds an entry for the caller of the logging function and hands it
+// to the sinks, returning nil when the level is disabled for that service or
+// the entry is filtered out.
+// style overrides the sink theme when it is not empty.
+func (l *Logger) output(level Level, style, tag, format string, v []any) *ServiceLog {
+	e := newEntry(2+l.skip, level, style, tag)
+	if l.service != "" {
+		e.Service = l.service
+	}
+	if !Enabled(level, e.Service) {
+		return nil
+	}
+	e.Message = fmt.Sprintf(format, v...)
+	e.Fields = l.fields
+	e.sinks = l.sinks
+	if !dispatch(e) {
+		return nil
 	}
+	return e
+}
 
+// newEntry captures the caller skip frames above its own caller, the message is left empty.
+// frames of functions marked with Helper are skipped and ERROR and FATAL entries get a
+// stack trace when enabled.
+func newEntry(skip int, level Level, style, tag string) *ServiceLog {
+	e := &ServiceLog{
+		Time:  time.Now(),
+		Level: level,
+		Tag:   tag,
+		style: style,
+	}
+	withStack := (level == ERROR || level == FATAL) && StackTraceEnabled()
+	depth := 1
+	if withStack || hasHelpers() {
+		depth = maxStackDepth
+	}
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:depth])
+	frames := callerFrames(pcs[:n])
+	if len(frames) > 0 {
+		e.setFrame(frames[0])
+	}
+	if withStack {
+		e.Stack = formatStack(frames)
+	}
+	return e
 }
 
-func String(C, T, format string, v ...any) string {
-	msg := fmt.Sprintf(format, v...)
-	ts := time.Now().Format(time.Stamp)
-	if !TRACE {
-		// If TRACE is disabled we don't include the file and line number
-		return fmt.Sprintf(format, v...)
+// setCaller fills in file, line and service from a program counter
+func (e *ServiceLog) setCaller(pc uintptr) {
+	if pc == 0 {
+		return
 	}
-	_, file, line, ok := runtime.Caller(3)
-	if ok {
-		path := TrimToProjectRoot("dps_http", file)            // max 32 chars
-		tag := CenterTag(T, 9)                                 // padded 9 and centered tag
-		lineStr := fmt.Sprintf(":%4d", line)                   // pad line number
-		prefix := fmt.Sprintf("%s[%s%s] ", tag, path, lineStr) // final prefix
-		if LOGGER_enable_timestamp {
-			return fmt.Sprintf("%s %s%s", ts, prefix, msg)
-		}
-		return fmt.Sprintf("%s%s", prefix, msg)
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	e.setFrame(frame)
+	e.pc = pc
+}
+
+func (e *ServiceLog) setFrame(frame runtime.Frame) {
+	e.pc = frame.PC
+	e.File = modulePath(frame.File, frame.Function)
+	e.Line = frame.Line
+	e.Package = packagePath(frame.Function)
+	e.Service = serviceTag(packageName(frame.Function))
+}
+
+// tagLevel maps a display tag such as "[warn]" back to its level
+func tagLevel(tag string) Level {
+	name := strings.Trim(StripANSI(tag), "[]_ ")
+	if l, err := ParseLevel(name); err == nil {
+		return l
 	}
-	return msg
+	return INFO
 }
@@ -0,0 +1,148 @@
+// Package logtest captures log entries inside tests so they can be asserted on.
+//
+//	func TestUpload(t *testing.T) {
+//		t.Parallel()
+//		rec := logtest.Capture(t)
+//		upload(rec.Context(context.Background()))
+//		rec.AssertLogged(logs.WARN, "retrying")
+//		rec.AssertNoErrors()
+//	}
+//
+// Capture only sees entries written through its Logger or Context, so parallel
+// tests never see each other's output. CaptureAll also registers the recorder
+// as a sink to see the package level logs.* calls.
+package logtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/danmuck/dps_lib/logs"
+)
+
+// Recorder is a logs.Sink that keeps every entry it receives
+type Recorder struct {
+	t      testing.TB
+	logger *logs.Logger
+	all    bool // registered as a global sink by CaptureAll
+
+	mu      sync.Mutex
+	entries []logs.ServiceLog
+}
+
+// Capture returns a Recorder scoped to t. captured entries are printed with
+// t.Log when the test fails.
+func Capture(t testing.TB) *Recorder {
+	r := newRecorder(t)
+	r.logger = r.logger.WithSinks(r)
+	return r
+}
+
+// CaptureAll is Capture plus every entry logged while the test runs, through
+// a sink that is removed again on cleanup. parallel tests using CaptureAll
+// see each other's package level entries.
+func CaptureAll(t testing.TB) *Recorder {
+	r := newRecorder(t)
+	r.all = true
+	logs.AddSink(r)
+	t.Cleanup(func() {
+		logs.Drain()
+		logs.RemoveSink(r)
+	})
+	return r
+}
+
+func newRecorder(t testing.TB) *Recorder {
+	r := &Recorder{t: t, logger: logs.WithFields()}
+	t.Cleanup(r.report)
+	return r
+}
+
+// Logger returns a logs.Logger whose entries are captured by r
+func (r *Recorder) Logger() *logs.Logger {
+	return r.logger
+}
+
+// Context returns ctx carrying r's Logger for code that logs via logs.FromContext
+func (r *Recorder) Context(ctx context.Context) context.Context {
+	return logs.NewContext(ctx, r.logger)
+}
+
+func (r *Recorder) Enabled(logs.Level) bool {
+	return true
+}
+
+func (r *Recorder) Log(e *logs.ServiceLog) error {
+	r.mu.Lock()
+	r.entries = append(r.entries, *e)
+	r.mu.Unlock()
+	return nil
+}
+
+// Entries returns the captured entries in the order they were logged. scoped
+// entries reach r synchronously, CaptureAll waits for the async queue first.
+func (r *Recorder) Entries() []logs.ServiceLog {
+	if r.all {
+		logs.Drain()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]logs.ServiceLog(nil), r.entries...)
+}
+
+// Find returns the captured entries at level whose message contains substr,
+// an empty substr matches every message
+func (r *Recorder) Find(level logs.Level, substr string) []logs.ServiceLog {
+	var out []logs.ServiceLog
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Message, substr) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Reset drops the captured entries
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// AssertLogged fails the test unless an entry at level containing substr was captured
+func (r *Recorder) AssertLogged(level logs.Level, substr string) {
+	r.t.Helper()
+	if len(r.Find(level, substr)) == 0 {
+		r.t.Errorf("logtest: no %s entry containing %q was logged", level, substr)
+	}
+}
+
+// AssertNotLogged fails the test if an entry at level containing substr was captured
+func (r *Recorder) AssertNotLogged(level logs.Level, substr string) {
+	r.t.Helper()
+	for _, e := range r.Find(level, substr) {
+		r.t.Errorf("logtest: unexpected %s entry: %s", level, e.Message)
+	}
+}
+
+// AssertNoErrors fails the test for every ERROR or FATAL entry captured
+func (r *Recorder) AssertNoErrors() {
+	r.t.Helper()
+	for _, e := range r.Entries() {
+		if e.Level == logs.ERROR || e.Level == logs.FATAL {
+			r.t.Errorf("logtest: unexpected %s entry: %s", e.Level, e.Message)
+		}
+	}
+}
+
+// report prints the captured entries of a failed test
+func (r *Recorder) report() {
+	if !r.t.Failed() {
+		return
+	}
+	for _, e := range r.Entries() {
+		r.t.Logf("%s %s: %s", e.Level, e.Service, logs.TextFormatter(&e, ""))
+	}
+}
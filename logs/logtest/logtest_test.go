@@ -0,0 +1,73 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/danmuck/dps_lib/logs"
+)
+
+// fakeTB records failures instead of failing the surrounding test
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper()        {}
+func (f *fakeTB) Cleanup(func()) {}
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCaptureIsScoped(t *testing.T) {
+	for i := range 4 {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			rec := Capture(t)
+			logs.FromContext(rec.Context(context.Background())).Warn("retrying upload %d", i)
+			rec.Logger().With("attempt", 2).Info("upload %d done", i)
+			logs.Warn("package level entries are not captured")
+
+			if n := len(rec.Entries()); n != 2 {
+				t.Fatalf("captured %d entries, want 2", n)
+			}
+			rec.AssertLogged(logs.WARN, fmt.Sprintf("retrying upload %d", i))
+			rec.AssertNotLogged(logs.WARN, "package level")
+			rec.AssertNoErrors()
+		})
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tb := &fakeTB{}
+	rec := CaptureAll(tb)
+	defer logs.RemoveSink(rec)
+	logs.Err("connection refused")
+
+	rec.AssertNoErrors()
+	rec.AssertLogged(logs.WARN, "connection refused")
+	rec.AssertNotLogged(logs.ERROR, "refused")
+	if len(tb.errors) != 3 {
+		t.Errorf("got %d failures, want 3: %q", len(tb.errors), tb.errors)
+	}
+}
+
+func TestEntriesLeaveSummariesPending(t *testing.T) {
+	logs.SetRateLimit(logs.WARN, logs.RateLimit{Window: time.Minute, Burst: 1})
+	defer logs.ClearRateLimits()
+	all := CaptureAll(t)
+	rec := Capture(t)
+
+	logs.Warn("disk almost full")
+	logs.Warn("disk almost full")
+	rec.Logger().Info("scoped")
+	if n := len(rec.Entries()); n != 1 {
+		t.Fatalf("scoped capture has %d entries, want 1", n)
+	}
+	all.AssertNotLogged(logs.WARN, "repeated")
+
+	logs.Flush()
+	all.AssertLogged(logs.WARN, "repeated 1 times")
+}
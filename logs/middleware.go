@@ -0,0 +1,77 @@
+package logs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is read from incoming requests and set on every response
+const RequestIDHeader = "X-Request-ID"
+
+// key of the request Logger in the gin context
+const requestLoggerKey = "logs.request_logger"
+
+// Middleware logs every request through the logs package in place of gin.Logger().
+// each request gets an ID, reused from X-Request-ID when the client sent one, and
+// the level follows the status class: 5xx error, 4xx warn, everything else info.
+// handlers get a Logger carrying the request_id field from RequestLogger, and
+// code further down the call chain from FromContext(c.Request.Context()).
+//
+//	router.Use(gin.Recovery(), logs.Middleware())
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		l := std.With("request_id", id)
+		c.Set(requestLoggerKey, l)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), l))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+		l.WithService("http").With(
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", max(c.Writer.Size(), 0),
+			"client_ip", c.ClientIP(),
+		).Logf(statusLevel(status), "%s %s", c.Request.Method, path)
+	}
+}
+
+// RequestID returns the ID assigned to the request by Middleware
+func RequestID(c *gin.Context) string {
+	return c.Writer.Header().Get(RequestIDHeader)
+}
+
+// RequestLogger returns the Logger Middleware attached to the request,
+// falling back to the Logger carried by the request context
+func RequestLogger(c *gin.Context) *Logger {
+	if l, ok := c.Get(requestLoggerKey); ok {
+		if l, ok := l.(*Logger); ok {
+			return l
+		}
+	}
+	return FromContext(c.Request.Context())
+}
+
+func statusLevel(status int) Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return ERROR
+	case status >= http.StatusBadRequest:
+		return WARN
+	}
+	return INFO
+}
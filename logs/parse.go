@@ -0,0 +1,288 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timePrefix matches a leading timestamp in the TimeStamp, TimeRFC3339Nano or
+// TimeElapsed format
+var timePrefix = regexp.MustCompile(`^(?:([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d)|(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:\d\d))|\+\s*\d+\.\d+s) `)
+
+// tracePrefix matches the trace prefix written by TextFormatter once ANSI
+// styles and the timestamp are stripped: centered tag, padded caller and line.
+//
+//	[warn]  [       dps_lib/networks/utils.go: 120] message
+var tracePrefix = regexp.MustCompile(`^\s*(\[[^\]]*\])\s*\[\s*(.*?):\s*(\d+)\] ?(.*)$`)
+
+// leading ANSI style of a colored line
+var leadingStyle = regexp.MustCompile(`^(\x1b\[[0-9;]*m)+`)
+
+// Parser reads entries back from files written by the sinks in any of the
+// text, JSON or logfmt formats, one entry per line. in text files with trace
+// or timestamps enabled, lines without either prefix continue the previous
+// entry, e.g. multi line messages and stack traces. text timestamps are read
+// in the TimeStamp and TimeRFC3339Nano formats.
+//
+//	p := logs.NewParser(f)
+//	for {
+//		e, err := p.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+type Parser struct {
+	sc      *bufio.Scanner
+	line    int
+	pending *ServiceLog // entry being assembled from continuation lines
+	traced  bool        // a text line with a trace or time prefix was seen
+	now     time.Time   // year reference for time.Stamp timestamps
+}
+
+func NewParser(r io.Reader) *Parser {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Parser{sc: sc, now: time.Now()}
+}
+
+// ParseError reports a malformed line, the parser skips it and reading can go on
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Next returns the next entry or io.EOF. a malformed line is reported as a
+// *ParseError and skipped, so reading can go on after one. any other error
+// comes from the reader, or a line over 1 MiB, and ends the input: every
+// later call returns it again.
+func (p *Parser) Next() (*ServiceLog, error) {
+	for p.sc.Scan() {
+		p.line++
+		raw := p.sc.Text()
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		if p.pending != nil && p.traced && !isEntryStart(raw) {
+			p.pending.Message += "\n" + StripANSI(raw)
+			continue
+		}
+		e, err := p.parse(raw)
+		if err != nil {
+			return nil, &ParseError{Line: p.line, Err: err}
+		}
+		if prev := p.pending; prev != nil {
+			p.pending = e
+			return prev, nil
+		}
+		p.pending = e
+	}
+	if e := p.pending; e != nil {
+		p.pending = nil
+		return e, nil
+	}
+	if err := p.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ParseLine parses a single line in any of the supported formats
+func ParseLine(line string) (*ServiceLog, error) {
+	return (&Parser{now: time.Now()}).parse(line)
+}
+
+func (p *Parser) parse(raw string) (*ServiceLog, error) {
+	trimmed := strings.TrimSpace(StripANSI(raw))
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return parseStructured(parseJSONObject(trimmed))
+	case strings.HasPrefix(trimmed, "time=") || strings.HasPrefix(trimmed, "level="):
+		return parseStructured(parseLogfmt(trimmed))
+	}
+	return p.parseText(raw), nil
+}
+
+func isEntryStart(raw string) bool {
+	line := StripANSI(raw)
+	trimmed := strings.TrimSpace(line)
+	return timePrefix.MatchString(line) || tracePrefix.MatchString(line) || strings.HasPrefix(trimmed, "{") ||
+		strings.HasPrefix(trimmed, "time=") || strings.HasPrefix(trimmed, "level=")
+}
+
+// parseText reads a TextFormatter line. the service is derived from the
+// caller's directory since text output does not carry it, so it matches the
+// logger's tag only where the package is named after its directory: entries
+// of a main package in cmd/logq are tagged "main" when logged and "logq"
+// here. without the trace prefix only the message is known and the level is
+// guessed from the DefaultTheme color.
+func (p *Parser) parseText(raw string) *ServiceLog {
+	line := StripANSI(raw)
+	level := styleLevel(raw)
+	e := &ServiceLog{Level: level, Tag: levelTags[level], Message: line}
+	if m := timePrefix.FindStringSubmatch(line); m != nil {
+		p.traced = true
+		switch {
+		case m[1] != "":
+			e.Time = p.stampTime(m[1])
+		case m[2] != "":
+			e.Time, _ = time.Parse(time.RFC3339Nano, m[2])
+		}
+		line = line[len(m[0]):]
+		e.Message = line
+	}
+	m := tracePrefix.FindStringSubmatch(line)
+	if m == nil {
+		return e
+	}
+	p.traced = true
+	e.Tag, e.Level, e.Message = m[1], tagLevel(m[1]), m[4]
+	e.File = strings.TrimPrefix(strings.TrimSpace(m[2]), "...")
+	e.Line, _ = strconv.Atoi(m[3])
+	if dir := path.Base(path.Dir(e.File)); dir != "." && dir != "/" {
+		e.Service = serviceTag(dir)
+	}
+	return e
+}
+
+// stampTime resolves a time.Stamp, which has no year, to the latest matching
+// time not after the parser's reference time
+func (p *Parser) stampTime(s string) time.Time {
+	t, err := time.ParseInLocation(time.Stamp, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	t = t.AddDate(p.now.Year(), 0, 0)
+	if t.After(p.now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// styleLevel maps the leading color of a line back to its DefaultTheme level
+func styleLevel(raw string) Level {
+	style := leadingStyle.FindString(raw)
+	if style == "" {
+		return INFO
+	}
+	for _, l := range []Level{DEBUG, INIT, INFO, WARN, ERROR, FATAL} {
+		if DefaultTheme[l] != "" && strings.HasSuffix(style, DefaultTheme[l]) {
+			return l
+		}
+	}
+	return INFO
+}
+
+// parseStructured builds an entry from the keys written by structuredFields
+func parseStructured(fields []Field, err error) (*ServiceLog, error) {
+	if err != nil {
+		return nil, err
+	}
+	e := &ServiceLog{Level: INFO}
+	for _, f := range fields {
+		s, isString := f.Value.(string)
+		switch f.Key {
+		case "time":
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				e.Time = t
+				continue
+			}
+		case "level":
+			if l, err := ParseLevel(s); err == nil {
+				e.Level = l
+				continue
+			}
+		case "service", "msg", "stack":
+			if isString {
+				switch f.Key {
+				case "service":
+					e.Service = s
+				case "msg":
+					e.Message = s
+				default:
+					e.Stack = s
+				}
+				continue
+			}
+		case "caller":
+			if i := strings.LastIndex(s, ":"); i != -1 {
+				e.File = s[:i]
+				e.Line, _ = strconv.Atoi(s[i+1:])
+				continue
+			}
+		}
+		f.Key = strings.TrimPrefix(f.Key, "field.")
+		e.Fields = append(e.Fields, f)
+	}
+	e.Tag = levelTags[e.Level]
+	return e, nil
+}
+
+// parseJSONObject decodes a flat JSON object keeping its key order
+func parseJSONObject(s string) ([]Field, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("logs: parse: not a JSON object")
+	}
+	var fields []Field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("logs: parse: %w", err)
+		}
+		key, _ := tok.(string)
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("logs: parse: %s: %w", key, err)
+		}
+		fields = append(fields, Field{Key: key, Value: v})
+	}
+	return fields, nil
+}
+
+// parseLogfmt splits key=value pairs, quoted values are unquoted
+func parseLogfmt(s string) ([]Field, error) {
+	var fields []Field
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " ") {
+		eq := strings.IndexAny(s, "= ")
+		if eq == -1 || s[eq] == ' ' {
+			// a bare key
+			end := strings.IndexByte(s+" ", ' ')
+			fields = append(fields, Field{Key: s[:end], Value: true})
+			s = s[min(end, len(s)):]
+			continue
+		}
+		key, rest := s[:eq], s[eq+1:]
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("logs: parse: %s: %w", key, err)
+			}
+			v, _ := strconv.Unquote(quoted)
+			fields = append(fields, Field{Key: key, Value: v})
+			s = rest[len(quoted):]
+			continue
+		}
+		end := strings.IndexByte(rest+" ", ' ')
+		fields = append(fields, Field{Key: key, Value: rest[:end]})
+		s = rest[min(end, len(rest)):]
+	}
+	return fields, nil
+}
@@ -0,0 +1,42 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+)
+
+// dispatch runs an entry through the pipeline: filter rules, redaction,
+// counters, rate limits, the service buffers and finally the sinks, directly or through the
+// async queue. it reports whether the entry was kept.
+func dispatch(e *ServiceLog) bool {
+	if !FilterAllows(e) {
+		return false
+	}
+	redact(e)
+	count(e)
+	ok, summary := rateAllows(e)
+	if summary != nil {
+		emit(summary)
+	}
+	if !ok {
+		return false
+	}
+	emit(e)
+	return true
+}
+
+// emit stores an entry that passed the pipeline checks and writes it out
+func emit(e *ServiceLog) {
+	logger.record(e)
+	if !enqueue(e) {
+		writeSinks(e)
+	}
+	for _, s := range e.sinks {
+		if !s.Enabled(e.Level) {
+			continue
+		}
+		if err := s.Log(e); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: sink %T: %v\n", s, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateKey decides which entries count as repeats of each other
+type RateKey int
+
+const (
+	KeyMessage RateKey = iota // same service and message
+	KeyCaller                 // same file and line, whatever the message
+)
+
+// RateLimit suppresses repeats of an entry within a window. the first Burst
+// entries of a window pass, the rest are counted and reported by a single
+// summary when the window is over, even if no repeat follows:
+//
+//	message repeated 57 times in last 60s: FilterIOCounters: no interfaces
+type RateLimit struct {
+	Window time.Duration // 0 disables the limit
+	Burst  int           // entries let through per window, at least 1
+	Key    RateKey
+}
+
+// maxRateKeys bounds the tracked keys before expired ones are pruned
+const maxRateKeys = 4096
+
+type rateState struct {
+	window     time.Duration
+	start      time.Time
+	count      int         // entries seen in the current window
+	suppressed int         // entries held back in the current window
+	last       *ServiceLog // last suppressed entry, template of the summary
+	timer      *time.Timer // reports the suppressed entries at the end of the window
+}
+
+// active limits and their per key state, guarded by limits.mu
+var limits = struct {
+	mu    sync.Mutex
+	rules map[Level]RateLimit
+	seen  map[string]*rateState
+}{
+	rules: make(map[Level]RateLimit),
+	seen:  make(map[string]*rateState),
+}
+
+// SetRateLimit applies r to every entry at level l:
+//
+//	logs.SetRateLimit(logs.WARN, logs.RateLimit{Window: time.Minute, Burst: 1})
+func SetRateLimit(l Level, r RateLimit) {
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	if r.Window <= 0 {
+		delete(limits.rules, l)
+		return
+	}
+	limits.rules[l] = r
+}
+
+// RateLimits returns the active limits by level
+func RateLimits() map[Level]RateLimit {
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	out := make(map[Level]RateLimit, len(limits.rules))
+	for l, r := range limits.rules {
+		out[l] = r
+	}
+	return out
+}
+
+// ClearRateLimits removes every limit and forgets pending repeats
+func ClearRateLimits() {
+	limits.mu.Lock()
+	for _, s := range limits.seen {
+		s.stop()
+	}
+	limits.rules = make(map[Level]RateLimit)
+	limits.seen = make(map[string]*rateState)
+	limits.mu.Unlock()
+}
+
+// rateAllows reports whether e passes its level's limit. summary is set when
+// e starts a new window after repeats were suppressed in the previous one.
+func rateAllows(e *ServiceLog) (ok bool, summary *ServiceLog) {
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	r, found := limits.rules[e.Level]
+	if !found {
+		return true, nil
+	}
+	key := r.key(e)
+	s := limits.seen[key]
+	if s == nil {
+		if len(limits.seen) >= maxRateKeys {
+			pruneRates(e.Time)
+		}
+		limits.seen[key] = &rateState{window: r.Window, start: e.Time, count: 1}
+		return true, nil
+	}
+	if e.Time.Sub(s.start) >= s.window {
+		summary = s.summary(e.Time)
+		s.stop()
+		*s = rateState{window: r.Window, start: e.Time, count: 1}
+		return true, summary
+	}
+	s.count++
+	if s.count <= max(r.Burst, 1) {
+		return true, nil
+	}
+	if s.suppressed == 0 {
+		start := s.start
+		s.timer = time.AfterFunc(time.Until(start.Add(s.window)), func() { reportWindow(key, s, start) })
+	}
+	s.suppressed++
+	s.last = e
+	return false, nil
+}
+
+// reportWindow emits the summary of a window that ended without another
+// repeat, unless a repeat, Flush or ClearRateLimits reported it already
+func reportWindow(key string, s *rateState, start time.Time) {
+	limits.mu.Lock()
+	var summary *ServiceLog
+	if limits.seen[key] == s && s.start.Equal(start) {
+		summary = s.summary(time.Now())
+		s.suppressed, s.last, s.timer = 0, nil, nil
+	}
+	limits.mu.Unlock()
+	if summary != nil {
+		emit(summary)
+	}
+}
+
+// pendingSummaries reports every suppressed repeat so far and resets the counts
+func pendingSummaries() []*ServiceLog {
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	now := time.Now()
+	var out []*ServiceLog
+	for _, s := range limits.seen {
+		if s.suppressed == 0 {
+			continue
+		}
+		out = append(out, s.summary(now))
+		s.stop()
+		s.suppressed, s.last = 0, nil
+	}
+	return out
+}
+
+// stop cancels the pending end of window report
+func (s *rateState) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func (r RateLimit) key(e *ServiceLog) string {
+	if r.Key == KeyCaller {
+		return e.Level.String() + "\x00" + e.File + ":" + strconv.Itoa(e.Line)
+	}
+	return e.Level.String() + "\x00" + e.Service + "\x00" + e.Message
+}
+
+// summary builds the entry reporting the suppressed repeats, nil if there were none
+func (s *rateState) summary(now time.Time) *ServiceLog {
+	if s.suppressed == 0 {
+		return nil
+	}
+	e := *s.last
+	e.Time = now
+	e.Stack = ""
+	e.Message = fmt.Sprintf("message repeated %d times in last %s: %s", s.suppressed, formatWindow(s.window), s.last.Message)
+	return &e
+}
+
+// pruneRates forgets keys whose window ended without suppressed repeats
+func pruneRates(now time.Time) {
+	for key, s := range limits.seen {
+		if s.suppressed == 0 && now.Sub(s.start) >= s.window {
+			delete(limits.seen, key)
+		}
+	}
+}
+
+// formatWindow prints whole second windows as "60s" instead of "1m0s"
+func formatWindow(d time.Duration) string {
+	if d >= time.Second && d%time.Second == 0 {
+		return strconv.Itoa(int(d/time.Second)) + "s"
+	}
+	return d.String()
+}
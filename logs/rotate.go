@@ -0,0 +1,335 @@
+package logs
+
+import (
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// layout of the timestamp inserted into rotated file names,
+// e.g. "server.log" -> "server-2025-07-01T12-00-00.000.log.gz"
+const rotateTimeLayout = "2006-01-02T15-04-05.000"
+
+// RotateOptions configures a RotatingFile, zero values disable each feature
+type RotateOptions struct {
+	MaxSize    int64         // rotate before a write would grow the file past MaxSize bytes
+	Daily      bool          // rotate on the first write of a new local day
+	Compress   bool          // gzip rotated files
+	MaxBackups int           // keep at most MaxBackups rotated files
+	MaxAge     time.Duration // remove rotated files older than MaxAge
+}
+
+// RotatingFile is an io.WriteCloser that rotates the file at path by size or day.
+// rotated files are renamed with a timestamp, optionally compressed and pruned.
+type RotatingFile struct {
+	mu   sync.Mutex
+	path string
+	opts RotateOptions
+	file *os.File
+	size int64
+	day  string // local date of the open file
+
+	now  func() time.Time
+	bg   sync.WaitGroup // compression and pruning
+	bgmu sync.Mutex     // serializes background work
+	stop chan struct{}  // closes the SIGHUP watcher
+}
+
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// NewFileSink opens a rotating file at path that reopens on SIGHUP and
+// returns a sink writing to it. the formatter defaults to TextFormatter.
+func NewFileSink(path string, rotate RotateOptions, opts SinkOptions) (*WriterSink, error) {
+	rf, err := OpenRotatingFile(path, rotate)
+	if err != nil {
+		return nil, err
+	}
+	rf.ReopenOnSIGHUP()
+	return NewWriterSink(rf, opts), nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Flush commits the current file to stable storage
+func (rf *RotatingFile) Flush() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Sync()
+}
+
+// Rotate closes the current file, moves it aside and starts a new one
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+// Reopen closes and reopens the file at path without renaming anything.
+// use it after an external tool such as logrotate has moved the file.
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file != nil {
+		rf.file.Close()
+	}
+	return rf.open()
+}
+
+// ReopenOnSIGHUP calls Reopen every time the process receives SIGHUP until Close
+func (rf *RotatingFile) ReopenOnSIGHUP() {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.stop != nil {
+		return
+	}
+	rf.stop = make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func(stop chan struct{}) {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-sig:
+				if err := rf.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "logs: reopen %s: %v\n", rf.path, err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(rf.stop)
+}
+
+// Close closes the file and waits for pending compression
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	if rf.stop != nil {
+		close(rf.stop)
+		rf.stop = nil
+	}
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.mu.Unlock()
+	rf.bg.Wait()
+	return err
+}
+
+func (rf *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	rf.day = info.ModTime().Format(time.DateOnly)
+	if rf.size == 0 {
+		rf.day = rf.now().Format(time.DateOnly)
+	}
+	return nil
+}
+
+func (rf *RotatingFile) shouldRotate(n int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.opts.MaxSize > 0 && rf.size+n > rf.opts.MaxSize {
+		return true
+	}
+	return rf.opts.Daily && rf.now().Format(time.DateOnly) != rf.day
+}
+
+// rotate moves the current file aside and opens a new one. when that fails the
+// file at path is reopened so later writes still land somewhere.
+func (rf *RotatingFile) rotate() error {
+	if rf.file != nil {
+		err := rf.file.Close()
+		rf.file = nil
+		if err != nil {
+			return errors.Join(err, rf.open())
+		}
+	}
+	backup := rf.backupName(rf.now())
+	if err := os.Rename(rf.path, backup); err != nil && !os.IsNotExist(err) {
+		return errors.Join(err, rf.open())
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.bg.Add(1)
+	go func() {
+		defer rf.bg.Done()
+		rf.bgmu.Lock()
+		defer rf.bgmu.Unlock()
+		if rf.opts.Compress {
+			// a backup pruned by an earlier rotation is already gone
+			if err := compressFile(backup); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "logs: compress %s: %v\n", backup, err)
+			}
+		}
+		rf.prune()
+	}()
+	return nil
+}
+
+// backupName inserts the timestamp between the file name and its extension.
+// a sequence number follows the timestamp when a backup of the same
+// millisecond exists, e.g. "server-2025-07-01T12-00-00.000-1.log".
+func (rf *RotatingFile) backupName(t time.Time) string {
+	dir, base := filepath.Split(rf.path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext) + "-" + t.Format(rotateTimeLayout)
+	backup := filepath.Join(dir, name+ext)
+	for seq := 1; fileExists(backup) || fileExists(backup+".gz"); seq++ {
+		backup = filepath.Join(dir, name+"-"+strconv.Itoa(seq)+ext)
+	}
+	return backup
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// parseBackupStamp parses the timestamp and optional sequence number of a backup
+func parseBackupStamp(s string) (time.Time, int, bool) {
+	stamp, seq := s, 0
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		// the last dash of a bare timestamp is followed by "05.000", not a number
+		if n, err := strconv.Atoi(s[i+1:]); err == nil && n > 0 {
+			stamp, seq = s[:i], n
+		}
+	}
+	t, err := time.ParseInLocation(rotateTimeLayout, stamp, time.Local)
+	return t, seq, err == nil
+}
+
+// backups lists rotated files, newest first
+func (rf *RotatingFile) backups() ([]string, map[string]time.Time, error) {
+	dir, base := filepath.Split(rf.path)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0)
+	stamps := make(map[string]time.Time)
+	seqs := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, seq, ok := parseBackupStamp(strings.TrimPrefix(stamp, prefix))
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		names = append(names, path)
+		stamps[path] = t
+		seqs[path] = seq
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(stamps[b].Compare(stamps[a]), cmp.Compare(seqs[b], seqs[a]))
+	})
+	return names, stamps, nil
+}
+
+// prune removes rotated files beyond MaxBackups or older than MaxAge
+func (rf *RotatingFile) prune() {
+	if rf.opts.MaxBackups <= 0 && rf.opts.MaxAge <= 0 {
+		return
+	}
+	names, stamps, err := rf.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logs: prune %s: %v\n", rf.path, err)
+		return
+	}
+	var cutoff time.Time
+	if rf.opts.MaxAge > 0 {
+		cutoff = rf.now().Add(-rf.opts.MaxAge)
+	}
+	for i, name := range names {
+		tooMany := rf.opts.MaxBackups > 0 && i >= rf.opts.MaxBackups
+		tooOld := !cutoff.IsZero() && stamps[name].Before(cutoff)
+		if tooMany || tooOld {
+			os.Remove(name)
+		}
+	}
+}
+
+// compressFile gzips path into path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
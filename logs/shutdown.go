@@ -0,0 +1,129 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long Fatal waits for the shutdown hooks
+const DefaultShutdownTimeout = 5 * time.Second
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// registered hooks and exit settings, guarded by shutdown.mu
+var shutdown = struct {
+	mu      sync.Mutex
+	hooks   []shutdownHook
+	timeout time.Duration
+	exit    func(code int)
+	exiting atomic.Bool // set while Fatal is shutting down
+}{
+	timeout: DefaultShutdownTimeout,
+	exit:    os.Exit,
+}
+
+// OnShutdown registers a hook that Fatal and Shutdown run in registration order,
+// e.g. to stop a service before the process exits:
+//
+//	logs.OnShutdown("metrics", func(ctx context.Context) error { return svc.Down() })
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	shutdown.mu.Lock()
+	shutdown.hooks = append(shutdown.hooks, shutdownHook{name: name, fn: fn})
+	shutdown.mu.Unlock()
+}
+
+// SetShutdownTimeout bounds the total time Fatal spends running hooks
+func SetShutdownTimeout(d time.Duration) {
+	shutdown.mu.Lock()
+	shutdown.timeout = d
+	shutdown.mu.Unlock()
+}
+
+// SetExitFunc replaces os.Exit as the func Fatal exits through and returns the
+// previous one. tests can record the exit code instead of killing the binary:
+//
+//	prev := logs.SetExitFunc(func(code int) { exited = code })
+//	defer logs.SetExitFunc(prev)
+func SetExitFunc(fn func(code int)) func(code int) {
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	prev := shutdown.exit
+	shutdown.exit = fn
+	return prev
+}
+
+// Shutdown runs every hook in registration order until ctx is done, then writes
+// out queued entries and flushes the sinks. hook errors are logged and returned
+// joined together.
+func Shutdown(ctx context.Context) error {
+	shutdown.mu.Lock()
+	hooks := append([]shutdownHook(nil), shutdown.hooks...)
+	shutdown.mu.Unlock()
+
+	// hooks run in order on their own goroutine, results are logged here so a
+	// hook that outlives ctx cannot log after Shutdown returned
+	results := make(chan error, len(hooks))
+	go func() {
+		defer close(results)
+		for _, h := range hooks {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := h.fn(ctx); err != nil {
+				results <- fmt.Errorf("%s: %w", h.name, err)
+				continue
+			}
+			results <- nil
+		}
+	}()
+
+	var all []error
+wait:
+	for {
+		select {
+		case err, ok := <-results:
+			if !ok {
+				break wait
+			}
+			if err != nil {
+				Err("shutdown hook %v", err)
+				all = append(all, err)
+			}
+		case <-ctx.Done():
+			Err("shutdown hooks did not finish: %v", ctx.Err())
+			all = append(all, ctx.Err())
+			break wait
+		}
+	}
+	err := errors.Join(all...)
+	if ferr := Flush(); ferr != nil {
+		err = errors.Join(err, ferr)
+	}
+	return err
+}
+
+// exit shuts down within the configured timeout and calls the exit func.
+// a Fatal raised by a hook skips the hooks and only flushes before exiting.
+func exit(code int) {
+	shutdown.mu.Lock()
+	timeout, exitFn := shutdown.timeout, shutdown.exit
+	shutdown.mu.Unlock()
+
+	if shutdown.exiting.CompareAndSwap(false, true) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		Shutdown(ctx)
+		cancel()
+		shutdown.exiting.Store(false)
+	} else {
+		Flush()
+	}
+	exitFn(code)
+}
@@ -0,0 +1,184 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Sink receives every entry that passes the level gate of its service.
+// Implementations must be safe for concurrent use.
+type Sink interface {
+	Enabled(l Level) bool    // minimum level check for this sink
+	Log(e *ServiceLog) error // write a single entry
+}
+
+// Flusher is implemented by sinks and writers that buffer output
+type Flusher interface {
+	Flush() error
+}
+
+// Formatter renders an entry as a single line without the trailing newline.
+// style is the ANSI style to apply, or "" for plain output.
+type Formatter func(e *ServiceLog, style string) string
+
+// SinkOptions configures a WriterSink
+type SinkOptions struct {
+	Level     Level     // minimum level written by the sink
+	Formatter Formatter // defaults to TextFormatter
+	Color     ColorMode // defaults to ColorAuto
+	Theme     Theme     // level styles used when colored, defaults to DefaultTheme
+}
+
+// WriterSink formats entries and writes them to an io.Writer, one per line
+type WriterSink struct {
+	mu    sync.Mutex
+	w     io.Writer
+	opts  SinkOptions
+	color bool // Color resolved against w
+}
+
+func NewWriterSink(w io.Writer, opts SinkOptions) *WriterSink {
+	if opts.Formatter == nil {
+		opts.Formatter = TextFormatter
+	}
+	return &WriterSink{w: w, opts: opts, color: useColor(w, opts.Color)}
+}
+
+func (s *WriterSink) Enabled(l Level) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return l >= s.opts.Level
+}
+
+func (s *WriterSink) Log(e *ServiceLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	style := ""
+	if s.color {
+		style = e.style
+		if style == "" {
+			style = s.opts.Theme.Style(e.Level)
+		}
+	}
+	_, err := io.WriteString(s.w, s.opts.Formatter(e, style)+"\n")
+	return err
+}
+
+func (s *WriterSink) SetLevel(l Level) {
+	s.mu.Lock()
+	s.opts.Level = l
+	s.mu.Unlock()
+}
+
+func (s *WriterSink) SetFormatter(f Formatter) {
+	if f == nil {
+		f = TextFormatter
+	}
+	s.mu.Lock()
+	s.opts.Formatter = f
+	s.mu.Unlock()
+}
+
+func (s *WriterSink) SetColor(mode ColorMode) {
+	s.mu.Lock()
+	s.opts.Color = mode
+	s.color = useColor(s.w, mode)
+	s.mu.Unlock()
+}
+
+func (s *WriterSink) SetTheme(t Theme) {
+	s.mu.Lock()
+	s.opts.Theme = t
+	s.mu.Unlock()
+}
+
+// Flush flushes the underlying writer if it is a Flusher
+func (s *WriterSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the underlying writer if it is an io.Closer
+func (s *WriterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Stdout is the default sink, text on standard output colored when it is a terminal
+var Stdout = NewWriterSink(os.Stdout, SinkOptions{Level: DEBUG, Color: ColorAuto})
+
+// registered sinks, guarded by sinks.mu
+var sinks = struct {
+	mu   sync.RWMutex
+	list []Sink
+}{
+	list: []Sink{Stdout},
+}
+
+// AddSink registers an additional sink
+func AddSink(s Sink) {
+	sinks.mu.Lock()
+	sinks.list = append(sinks.list, s)
+	sinks.mu.Unlock()
+}
+
+// RemoveSink unregisters a sink, it is a no-op if the sink is not registered
+func RemoveSink(s Sink) {
+	sinks.mu.Lock()
+	sinks.list = slices.DeleteFunc(sinks.list, func(x Sink) bool { return x == s })
+	sinks.mu.Unlock()
+}
+
+// SetSinks replaces every registered sink, including Stdout
+func SetSinks(s ...Sink) {
+	sinks.mu.Lock()
+	sinks.list = slices.Clone(s)
+	sinks.mu.Unlock()
+}
+
+// Sinks returns the registered sinks
+func Sinks() []Sink {
+	sinks.mu.RLock()
+	defer sinks.mu.RUnlock()
+	return slices.Clone(sinks.list)
+}
+
+// writeSinks fans an entry out to every sink that accepts its level.
+// sink errors are reported on stderr so a broken sink never blocks the others.
+func writeSinks(e *ServiceLog) {
+	sinks.mu.RLock()
+	defer sinks.mu.RUnlock()
+	for _, s := range sinks.list {
+		if !s.Enabled(e.Level) {
+			continue
+		}
+		if err := s.Log(e); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: sink %T: %v\n", s, err)
+		}
+	}
+}
+
+// flushSinks flushes every registered sink that buffers output
+func flushSinks() error {
+	var errs []error
+	for _, s := range Sinks() {
+		if f, ok := s.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, fmt.Errorf("sink %T: %w", s, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,177 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// slog levels used for the levels slog has no name for
+const (
+	slogLevelInit  = slog.LevelDebug + 2
+	slogLevelFatal = slog.LevelError + 4
+	slogLevelDev   = slog.LevelError + 8
+)
+
+// SlogLevel maps a Level onto the slog scale, keeping DEV above FATAL:
+//
+//	DEBUG -4, INIT -2, INFO 0, WARN 4, ERROR 8, FATAL 12, DEV 16
+func SlogLevel(l Level) slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case INIT:
+		return slogLevelInit
+	case INFO:
+		return slog.LevelInfo
+	case DEV:
+		return slogLevelDev
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	}
+	return slogLevelFatal
+}
+
+// LevelFromSlog is the inverse of SlogLevel, levels in between round down
+func LevelFromSlog(l slog.Level) Level {
+	switch {
+	case l == slogLevelInit:
+		return INIT
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	case l < slogLevelFatal:
+		return ERROR
+	case l < slogLevelDev:
+		return FATAL
+	}
+	return DEV
+}
+
+// SlogHandler is a slog.Handler that writes records through the logs sinks,
+// so slog output gets the same levels, colors, trace prefix and service gating.
+// a string "service" attribute overrides the service tag taken from the caller.
+//
+//	slog.SetDefault(slog.New(logs.NewSlogHandler()))
+type SlogHandler struct {
+	fields []Field
+	prefix string // open groups joined with "."
+}
+
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := minLevel()
+	return min != INACTIVE && LevelFromSlog(level) >= min
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	level := LevelFromSlog(r.Level)
+	e := &ServiceLog{
+		Time:    r.Time,
+		Level:   level,
+		Tag:     levelTags[level],
+		Message: r.Message,
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.setCaller(r.PC)
+
+	fields := slices.Clone(h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	for i, f := range fields {
+		if service, ok := f.Value.(string); ok && f.Key == "service" {
+			e.Service = service
+			fields = slices.Delete(fields, i, i+1)
+			break
+		}
+	}
+	e.Fields = fields
+
+	if !Enabled(level, e.Service) {
+		return nil
+	}
+	dispatch(e)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := slices.Clone(h.fields)
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{fields: fields, prefix: h.prefix}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// appendAttr flattens an attribute into fields, group members become "group.key"
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
+
+// SlogSink forwards entries into a *slog.Logger.
+// the service tag and fields are passed along as attributes.
+type SlogSink struct {
+	logger *slog.Logger
+}
+
+func NewSlogSink(l *slog.Logger) *SlogSink {
+	return &SlogSink{logger: l}
+}
+
+func (s *SlogSink) Enabled(l Level) bool {
+	return s.logger.Handler().Enabled(context.Background(), SlogLevel(l))
+}
+
+func (s *SlogSink) Log(e *ServiceLog) error {
+	r := slog.NewRecord(e.Time, SlogLevel(e.Level), e.Message, e.pc)
+	if e.Service != "" {
+		r.AddAttrs(slog.String("service", e.Service))
+	}
+	for _, f := range e.Fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	return s.logger.Handler().Handle(context.Background(), r)
+}
+
+// ForwardToSlog replaces every sink with a SlogSink so logs.* output ends up in l.
+// a logger backed by a SlogHandler is rejected since entries would loop back here.
+func ForwardToSlog(l *slog.Logger) error {
+	if _, ok := l.Handler().(*SlogHandler); ok {
+		return errors.New("logs: cannot forward into a logger backed by logs.SlogHandler")
+	}
+	SetSinks(NewSlogSink(l))
+	return nil
+}
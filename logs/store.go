@@ -0,0 +1,218 @@
+package logs
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize is the number of entries kept per service tag
+const DefaultBufferSize = 1000
+
+// ServiceLogger keeps a bounded ring buffer of recent entries per service tag
+type ServiceLogger struct {
+	mu   sync.RWMutex
+	size int
+	logs map[string]*ring
+	subs map[chan ServiceLog]struct{}
+}
+
+var logger *ServiceLogger = NewServiceLogger(DefaultBufferSize)
+
+func NewServiceLogger(size int) *ServiceLogger {
+	return &ServiceLogger{
+		size: max(size, 1),
+		logs: make(map[string]*ring),
+		subs: make(map[chan ServiceLog]struct{}),
+	}
+}
+
+// LogQuery selects entries from a ServiceLogger, zero values match everything
+type LogQuery struct {
+	Service  string    `json:"service"`  // service tag, "" for all
+	Level    Level     `json:"level"`    // minimum level
+	Since    time.Time `json:"since"`    // inclusive lower bound
+	Until    time.Time `json:"until"`    // exclusive upper bound
+	Contains string    `json:"contains"` // case insensitive message substring
+	Limit    int       `json:"limit"`    // keep only the newest Limit matches
+}
+
+// Match reports whether e satisfies every condition of q
+func (q LogQuery) Match(e *ServiceLog) bool {
+	if q.Service != "" && e.Service != q.Service {
+		return false
+	}
+	if e.Level < q.Level {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	if q.Contains != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(q.Contains)) {
+		return false
+	}
+	return true
+}
+
+// Query returns matching entries from the package buffers, oldest first, e.g.
+// the last 200 metrics errors:
+//
+//	logs.Query(logs.LogQuery{Service: "metrics", Level: logs.ERROR, Limit: 200})
+func Query(q LogQuery) []ServiceLog {
+	return logger.Query(q)
+}
+
+// ServiceTags lists the service tags that have buffered entries
+func ServiceTags() []string {
+	return logger.Services()
+}
+
+// Subscribe follows new entries on the package buffers, see (*ServiceLogger).Subscribe
+func Subscribe(buf int) (<-chan ServiceLog, func()) {
+	return logger.Subscribe(buf)
+}
+
+// SetBufferSize changes the number of entries kept per service tag.
+// existing buffers keep their newest entries.
+func SetBufferSize(n int) {
+	logger.Resize(n)
+}
+
+func (sl *ServiceLogger) Query(q LogQuery) []ServiceLog {
+	// the buffer key selects the service, Log entries can be buffered under several
+	match := q
+	match.Service = ""
+
+	sl.mu.RLock()
+	matches := make([]ServiceLog, 0)
+	for service, r := range sl.logs {
+		if q.Service != "" && service != q.Service {
+			continue
+		}
+		r.each(func(e *ServiceLog) {
+			if q.Service == "" && e.Service != service {
+				return // extra copy of a Log entry, reported from its own buffer
+			}
+			if match.Match(e) {
+				matches = append(matches, *e)
+			}
+		})
+	}
+	sl.mu.RUnlock()
+
+	slices.SortStableFunc(matches, func(a, b ServiceLog) int { return a.Time.Compare(b.Time) })
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[len(matches)-q.Limit:]
+	}
+	return matches
+}
+
+func (sl *ServiceLogger) Services() []string {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	services := make([]string, 0, len(sl.logs))
+	for service := range sl.logs {
+		services = append(services, service)
+	}
+	slices.Sort(services)
+	return services
+}
+
+func (sl *ServiceLogger) Resize(n int) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	sl.size = max(n, 1)
+	for service, r := range sl.logs {
+		resized := newRing(sl.size)
+		r.each(func(e *ServiceLog) { resized.push(*e) })
+		sl.logs[service] = resized
+	}
+}
+
+// Clear drops every buffered entry
+func (sl *ServiceLogger) Clear() {
+	sl.mu.Lock()
+	sl.logs = make(map[string]*ring)
+	sl.mu.Unlock()
+}
+
+// Subscribe returns a channel that receives every entry recorded after the call
+// and a cancel func that must be called to release it. entries are dropped for
+// a subscriber whose buffer of buf entries is full, logging never blocks on a reader.
+func (sl *ServiceLogger) Subscribe(buf int) (<-chan ServiceLog, func()) {
+	ch := make(chan ServiceLog, max(buf, 1))
+	sl.mu.Lock()
+	sl.subs[ch] = struct{}{}
+	sl.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			sl.mu.Lock()
+			delete(sl.subs, ch)
+			close(ch)
+			sl.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// record buffers e under its own service and publishes it to subscribers
+func (sl *ServiceLogger) record(e *ServiceLog) {
+	sl.store(e.Service, e)
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	for ch := range sl.subs {
+		select {
+		case ch <- *e:
+		default:
+		}
+	}
+}
+
+// store copies e into the buffer for service
+func (sl *ServiceLogger) store(service string, e *ServiceLog) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	r, ok := sl.logs[service]
+	if !ok {
+		r = newRing(sl.size)
+		sl.logs[service] = r
+	}
+	r.push(*e)
+}
+
+// ring is a fixed size buffer that overwrites its oldest entry when full
+type ring struct {
+	entries []ServiceLog
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]ServiceLog, size)}
+}
+
+func (r *ring) push(e ServiceLog) {
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// each visits the entries oldest first
+func (r *ring) each(fn func(e *ServiceLog)) {
+	if r.full {
+		for i := r.next; i < len(r.entries); i++ {
+			fn(&r.entries[i])
+		}
+	}
+	for i := 0; i < r.next; i++ {
+		fn(&r.entries[i])
+	}
+}
@@ -0,0 +1,166 @@
+package logs
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeat keeps idle streams alive through proxies
+const streamHeartbeat = 15 * time.Second
+
+// LogService serves the service buffers over HTTP:
+//
+//	GET /logs         buffered entries as JSON
+//	GET /logs/stream  live entries as Server-Sent Events
+//
+// both accept the query params service, level, contains, since, until (RFC3339)
+// and limit. on /logs/stream limit is the number of buffered entries sent
+// before following live ones.
+type LogService struct {
+	version string
+	store   *ServiceLogger
+
+	running bool
+	done    chan struct{} // closed by Down to end open streams
+
+	mu sync.Mutex
+}
+
+func NewLogService() *LogService {
+	return &LogService{
+		version: "v1",
+		store:   logger,
+		running: true,
+		done:    make(chan struct{}),
+	}
+}
+
+//	Service interface implementation
+//
+// //
+func (svc *LogService) Up(rg *gin.RouterGroup) {
+	Init("Register logs.%s", svc.version)
+	group := rg.Group("/logs")
+	// group.Use(middleware.JWTMiddleware(), middleware.AuthorizeByRoles("admin"))
+	group.GET("", QueryLogs(svc))
+	group.GET("/stream", StreamLogs(svc))
+}
+
+func (svc *LogService) Down() error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.running {
+		svc.running = false
+		close(svc.done)
+	}
+	Log("logs stream stopped")
+	return nil
+}
+
+func (svc *LogService) Version() string {
+	return svc.version
+}
+
+func (svc *LogService) DependsOn() []string {
+	return nil
+}
+
+func QueryLogs(svc *LogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, err := bindLogQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entries := svc.store.Query(q)
+		c.JSON(http.StatusOK, gin.H{
+			"entries": entries,
+			"count":   len(entries),
+		})
+	}
+}
+
+func StreamLogs(svc *LogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, err := bindLogQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// subscribe before reading the backlog so nothing falls in between
+		entries, cancel := svc.store.Subscribe(256)
+		defer cancel()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		if q.Limit > 0 {
+			for _, e := range svc.store.Query(q) {
+				c.SSEvent("log", e)
+			}
+		}
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(streamHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-svc.done:
+				return
+			case e := <-entries:
+				if !q.Match(&e) {
+					continue
+				}
+				c.SSEvent("log", e)
+				c.Writer.Flush()
+			case <-ticker.C:
+				fmt.Fprint(c.Writer, ": ping\n\n")
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// bindLogQuery reads a LogQuery from the request's query params
+func bindLogQuery(c *gin.Context) (LogQuery, error) {
+	q := LogQuery{
+		Service:  c.Query("service"),
+		Contains: c.Query("contains"),
+	}
+	if raw := c.Query("level"); raw != "" {
+		l, err := ParseLevel(raw)
+		if err != nil {
+			return q, err
+		}
+		q.Level = l
+	}
+	for param, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s: %w", param, err)
+		}
+		*dst = t
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return q, fmt.Errorf("invalid limit %q", raw)
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
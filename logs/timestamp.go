@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// named timestamp formats accepted by SetTimeFormat, anything else must be a
+// time layout
+const (
+	TimeStamp       = "stamp"       // Jan _2 15:04:05
+	TimeRFC3339Nano = "rfc3339nano" // 2006-01-02T15:04:05.999999999Z07:00
+	TimeElapsed     = "elapsed"     // seconds since the process started, e.g. "+   12.345s"
+)
+
+// processStart is the reference of TimeElapsed
+var processStart = time.Now()
+
+// SetTimeFormat selects how text output timestamps entries and enables them,
+// independent of trace mode. utc converts times to UTC first, which lines up
+// logs from services running in different zones. structured formatters always
+// write RFC3339Nano but honor utc.
+//
+//	logs.SetTimeFormat(logs.TimeRFC3339Nano, true)
+func SetTimeFormat(format string, utc bool) error {
+	format = strings.TrimSpace(format)
+	if format == "" {
+		return fmt.Errorf("logs: empty time format")
+	}
+	if named, ok := timeFormatName(format); ok {
+		format = named
+	} else if time.Unix(0, 0).Format(format) == format {
+		// a layout renders differently from itself, a typo renders unchanged
+		return fmt.Errorf("logs: unknown time format %q", format)
+	}
+	settings.mu.Lock()
+	settings.timeFormat = format
+	settings.timeUTC = utc
+	settings.timestamp = true
+	settings.mu.Unlock()
+	return nil
+}
+
+// TimeFormat returns the active timestamp format and whether it is in UTC
+func TimeFormat() (format string, utc bool) {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.timeFormat, settings.timeUTC
+}
+
+// FormatTime renders t in the active timestamp format
+func FormatTime(t time.Time) string {
+	format, utc := TimeFormat()
+	if utc {
+		t = t.UTC()
+	}
+	switch format {
+	case TimeStamp, "":
+		return t.Format(time.Stamp)
+	case TimeRFC3339Nano:
+		return t.Format(time.RFC3339Nano)
+	case TimeElapsed:
+		return fmt.Sprintf("+%9.3fs", t.Sub(processStart).Seconds())
+	}
+	return t.Format(format)
+}
+
+// structuredTime is the time written by the JSON and logfmt formatters
+func structuredTime(t time.Time) string {
+	if _, utc := TimeFormat(); utc {
+		t = t.UTC()
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func timeFormatName(s string) (string, bool) {
+	switch strings.ToLower(s) {
+	case "stamp":
+		return TimeStamp, true
+	case "rfc3339nano", "rfc3339":
+		return TimeRFC3339Nano, true
+	case "elapsed", "uptime":
+		return TimeElapsed, true
+	}
+	return s, false
+}
+
+// parseTimeSpec reads the DPS_LOG_TIME value: "off", or a format optionally
+// followed by ",utc" or ",local". a bare "utc" or "local" is the TimeStamp
+// format in that zone.
+func parseTimeSpec(spec string) (format string, utc, enabled bool, err error) {
+	format, zone, _ := strings.Cut(strings.TrimSpace(spec), ",")
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	switch strings.ToLower(format) {
+	case "off", "false", "0", "none":
+		return "", false, false, nil
+	case "on", "true", "1", "":
+		format = TimeStamp
+	case "utc", "local":
+		if zone == "" {
+			format, zone = TimeStamp, strings.ToLower(format)
+		}
+	}
+	if zone != "" && zone != "utc" && zone != "local" {
+		return "", false, false, fmt.Errorf("logs: unknown time zone %q, want utc or local", zone)
+	}
+	return format, zone == "utc", true, nil
+}
@@ -8,17 +8,23 @@ import (
 )
 
 // types
-type ServiceLog struct {
-	ts  time.Time
-	msg string
-}
 
-type ServiceLogger struct {
-	logs map[string]*ServiceLog
-}
-
-var logger *ServiceLogger = &ServiceLogger{
-	logs: make(map[string]*ServiceLog),
+// ServiceLog is a single log entry as it is handed to the sinks
+type ServiceLog struct {
+	Time    time.Time `json:"time"`
+	Level   Level     `json:"level"`
+	Service string    `json:"service"` // service tag of the calling package
+	Package string    `json:"package"` // import path of the calling package
+	Tag     string    `json:"tag"`     // display tag, e.g. "[error]"
+	File    string    `json:"file"`    // caller file relative to its module, e.g. "dps_lib/networks/utils.go"
+	Line    int       `json:"line"`    // caller line
+	Message string    `json:"msg"`
+	Fields  []Field   `json:"fields,omitempty"`
+	Stack   string    `json:"stack,omitempty"` // trimmed stack trace of ERROR and FATAL entries
+
+	sinks []Sink  // scoped sinks of the Logger that wrote the entry
+	style string  // ANSI style used by colored sinks
+	pc    uintptr // program counter of the caller, used by the slog bridge
 }
 
 const (
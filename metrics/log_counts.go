@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/danmuck/dps_lib/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// LogCounts serves the log entry counters per level and per service tag.
+// ?reset=true starts a new counting period after the snapshot is taken.
+func LogCounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counts := logs.Counts()
+		if c.Query("reset") == "true" {
+			logs.ResetCounts()
+		}
+		c.JSON(http.StatusOK, counts)
+	}
+}
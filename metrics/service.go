@@ -30,10 +30,11 @@ type UserMetricsService struct {
 //
 // //
 func (svc *UserMetricsService) Up(rg *gin.RouterGroup) {
-	logs.Init("Register %s", service.metricsDB)
+	logs.Init("Register %s", svc.metricsDB)
 	admin := rg.Group("/metrics")
 	// admin.Use(middleware.JWTMiddleware(), middleware.AuthorizeByRoles("admin"))
 	admin.GET("/users", UserGrowth(svc))
+	admin.GET("/logs", LogCounts())
 	// svc.start()
 }
 
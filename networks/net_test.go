@@ -1,11 +1,21 @@
 package networks
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"testing"
 
 	"github.com/danmuck/dps_lib/logs"
+	"github.com/danmuck/dps_lib/logs/logtest"
 	"github.com/shirou/gopsutil/net"
 )
 
@@ -47,9 +57,53 @@ const (
 	DefaultLabel        = "dummy.label"  // example packet label/identifier note:
 )
 
+// useReplay swaps the package source and clock for a replay of readings and a
+// fake clock for the rest of the test
+func useReplay(t *testing.T, readings ...[]net.IOCountersStat) *FakeClock {
+	t.Helper()
+	clock := NewFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prevSource := SetCounterSource(NewReplaySource(readings...))
+	prevClock := SetClock(clock)
+	t.Cleanup(func() {
+		SetCounterSource(prevSource)
+		SetClock(prevClock)
+	})
+	return clock
+}
+
+// reading builds counters for eth0 and lo, lo carries a fixed tenth of the traffic
+func reading(sent, recv, pktsSent, pktsRecv uint64) []net.IOCountersStat {
+	return []net.IOCountersStat{
+		{Name: "eth0", BytesSent: sent * 9 / 10, BytesRecv: recv * 9 / 10, PacketsSent: pktsSent * 9 / 10, PacketsRecv: pktsRecv * 9 / 10},
+		{Name: "lo", BytesSent: sent / 10, BytesRecv: recv / 10, PacketsSent: pktsSent / 10, PacketsRecv: pktsRecv / 10},
+	}
+}
+
 func TestNet(t *testing.T) {
-	tests := NewFrame("default", 1, 1)
-	logs.Dev(tests.String())
+	clock := useReplay(t, reading(1000, 2000, 10, 20), reading(3000, 6000, 30, 60))
+	start := clock.Now()
+	fr := NewFrame(AllInterfaces, 1, 2)
+	logs.Dev(fr.String())
+
+	want := Frame{
+		Source: AllInterfaces, Samples: 1, Duration_s: 2, Timestamp: start,
+		Sent_b: 2000 * 8, Recv_b: 4000 * 8, Sent_pkt: 20, Recv_pkt: 40,
+		Upload_bps: 8000, Download_bps: 16000, PktsUp_pps: 10, PktsDown_pps: 20,
+		AvgPktSize: 6000 * 8 / 60,
+	}
+	if fr.String() != want.String() {
+		t.Errorf("got %s\nwant %s", fr, &want)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 2*time.Second {
+		t.Errorf("NewFrame waited %v on the clock, want 2s", elapsed)
+	}
+
+	// rates follow the duration
+	fr.Duration_s = 4
+	fr.ComputeRates()
+	if fr.Upload_bps != 4000 || fr.PktsDown_pps != 10 {
+		t.Errorf("ComputeRates over 4s: up %v bps, down %v pps", fr.Upload_bps, fr.PktsDown_pps)
+	}
 }
 func TestNetStuff(t *testing.T) {
 	logs.ColorTest()
@@ -66,8 +120,13 @@ func TestNetStuff(t *testing.T) {
 	// logs.Dev("Response: %s", response.String())
 	io, _ := net.IOCounters(false)
 	logs.Dev("Network IO Counters: %v", io)
-	f := GenerateFrame(10, 10)
+	useReplay(t, reading(0, 0, 0, 0), reading(1000, 3000, 10, 30), reading(1500, 4000, 20, 40))
+	f := GenerateFrame(DefaultLinkDistance, 2)
 	logs.Dev("Generated Frame: %+v", f)
+	if f == nil || f.Samples != 2 || f.Duration_s != 2 || f.Source != AllInterfaces ||
+		f.Upload_bps != 1500*8/2 || f.Download_bps != 4000*8/2 {
+		t.Errorf("GenerateFrame = %v", f)
+	}
 	if response.FramesServiced == 0 {
 		t.Error("No packets serviced in the response")
 	} else {
@@ -134,6 +193,11 @@ func TestSweepingLambdaExt(t *testing.T) {
 
 		fmt.Printf("λ=%.1fp/s (/)  μ: %.2fp/s (=) ρ=%.2f, Wq=%.3fs, W=%.3fs \n",
 			λ, μ, λ/μ, w.QueueingDelay, w.ProcessingDelay+w.QueueingDelay)
+
+		// an M/M/1 queue only settles while λ < μ
+		if overloaded := math.IsInf(w.QueueingDelay, 1); overloaded != (λ >= μ) {
+			t.Errorf("λ=%.2f μ=%.2f: Wq=%v", λ, μ, w.QueueingDelay)
+		}
 	}
 	logs.Warn(`
 	// 	λ 			Arrival rate in packets/sec (how fast requests come in)
@@ -143,11 +207,6 @@ func TestSweepingLambdaExt(t *testing.T) {
 	// 	W = Wq + 1/μ 		Average system time in an M/M/1 model:
 	`)
 
-	if true {
-		t.Log("Network propagation speed test passed.")
-	} else {
-		t.Error("Network propagation speed test failed.")
-	}
 }
 
 func TestSweepingLambda(t *testing.T) {
@@ -186,6 +245,7 @@ func TestSweepingLambda(t *testing.T) {
 }
 func TestNetworkingConcepts(t *testing.T) {
 	logs.ColorTest()
+	rec := logtest.CaptureAll(t)
 	logs.Dev("========[TestNetworkingConcepts]========")
 
 	server_1r := NewServiceParams(DefaultLinkDistance, DefaultDataRate, DefaultPacketSize, DefaultPackets, "[ 1 ]")
@@ -211,9 +271,325 @@ func TestNetworkingConcepts(t *testing.T) {
 	logs.Info("Utilization (Persistent): %.2f%%, Utilization (Non-Persistent): %.2f%%",
 		utest_util*100, ntest_util*100)
 
-	if true {
-		t.Log("Network propagation speed test passed.")
-	} else {
-		t.Error("Network propagation speed test failed.")
+	if utest_util <= 0 || utest_util > 1 || ntest_util <= 0 || ntest_util > utest_util {
+		t.Errorf("utilization out of range: persistent %.4f, non-persistent %.4f", utest_util, ntest_util)
+	}
+	rec.AssertLogged(logs.INFO, "Running network utilization query")
+	rec.AssertNotLogged(logs.WARN, "No files in query response")
+	rec.AssertNoErrors()
+}
+
+func TestSampler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewSampler(SamplerOptions{Interval: 20 * time.Millisecond, PerNIC: true})
+	if err := s.Start(ctx); err != nil {
+		t.Skipf("no IO counters: %v", err)
+	}
+	if err := s.Start(ctx); err == nil {
+		t.Error("second Start should fail")
+	}
+	seen := make(map[string]int)
+	for fr := range s.Frames() {
+		if fr.Source == "" || fr.Duration_s <= 0 || fr.Upload_bps < 0 {
+			t.Errorf("bad frame %v", fr)
+		}
+		if seen[fr.Source]++; seen[fr.Source] == 3 {
+			cancel()
+		}
+	}
+	<-s.Done()
+	if len(seen) == 0 {
+		t.Fatal("no frames before shutdown")
+	}
+
+	// aggregate frames of a replay through a callback, the sampler stops with the replay
+	var frames []*Frame
+	s = NewSampler(SamplerOptions{
+		Interval: time.Second,
+		Source:   NewReplaySource(reading(0, 0, 0, 0), reading(1000, 2000, 10, 20), reading(1000, 2000, 10, 20)),
+		Clock:    NewFakeClock(time.Now()),
+		OnFrame:  func(fr *Frame) { frames = append(frames, fr) },
+	})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done()
+	if len(frames) != 2 || frames[0].Source != AllInterfaces || frames[0].Upload_bps != 8000 || frames[1].Upload_bps != 0 {
+		t.Errorf("aggregate frames = %v", frames)
+	}
+}
+
+// failingSource returns its readings, then fails every read
+type failingSource struct {
+	replay *ReplaySource
+	reads  int
+}
+
+func (f *failingSource) IOCounters(pernic bool) ([]net.IOCountersStat, error) {
+	f.reads++
+	if f.replay.Remaining() == 0 {
+		return nil, errors.New("counters unavailable")
+	}
+	return f.replay.IOCounters(pernic)
+}
+
+func TestGenerateFrameStops(t *testing.T) {
+	// durations round up to whole seconds
+	useReplay(t, reading(0, 0, 0, 0), reading(1000, 0, 10, 0), reading(2000, 0, 20, 0), reading(3000, 0, 30, 0))
+	if f := GenerateFrame(DefaultLinkDistance, 2.5); f == nil || f.Samples != 3 || f.Sent_pkt != 30 {
+		t.Errorf("2.5s frame = %v", f)
+	}
+
+	// failing reads end the frame with the samples taken so far
+	src := &failingSource{replay: NewReplaySource(reading(0, 0, 0, 0), reading(1000, 0, 10, 0))}
+	prevSource := SetCounterSource(src)
+	defer SetCounterSource(prevSource)
+	done := make(chan *Frame)
+	go func() { done <- GenerateFrame(DefaultLinkDistance, 60) }()
+	select {
+	case f := <-done:
+		if f == nil || f.Samples != 1 || f.Sent_pkt != 10 {
+			t.Errorf("partial frame = %v", f)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GenerateFrame did not return while reads fail")
+	}
+	if src.reads != 2+DefaultMaxErrors {
+		t.Errorf("read %d times, want %d", src.reads, 2+DefaultMaxErrors)
+	}
+
+	s := NewSampler(SamplerOptions{Source: &failingSource{replay: NewReplaySource(reading(0, 0, 0, 0))}, Clock: NewFakeClock(time.Now()), MaxErrors: 2})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done()
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "2 failed reads") {
+		t.Errorf("sampler error = %v", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecordingSource(NewReplaySource(reading(100, 200, 1, 2), reading(300, 400, 3, 4)), &buf)
+	if agg, err := rec.IOCounters(false); err != nil || len(agg) != 1 || agg[0].BytesSent != 100 {
+		t.Fatalf("aggregate = %v, %v", agg, err)
+	}
+	if _, err := rec.IOCounters(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.IOCounters(true); !errors.Is(err, ErrReplayDone) {
+		t.Errorf("err = %v, want ErrReplayDone", err)
+	}
+
+	replay, err := LoadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Remaining() != 2 {
+		t.Fatalf("loaded %d readings, want 2", replay.Remaining())
+	}
+	replay.IOCounters(true)
+	if nics, _ := replay.IOCounters(true); len(nics) != 2 || nics[0].Name != "eth0" || nics[0].BytesRecv != 360 {
+		t.Errorf("replayed %v", nics)
+	}
+}
+
+// writeFiles creates files below root, keyed by slash separated path
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const netDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func TestProcSource(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/net/dev": netDevHeader +
+			"    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0\n" +
+			"  eth0: 2000000    2000    4    6    1     2          0        30   500000    1000    2    8    3     5       7          0\n",
+		"sys/class/net/eth0/speed":     "1000\n",
+		"sys/class/net/eth0/mtu":       "1500\n",
+		"sys/class/net/eth0/operstate": "up\n",
+		"sys/class/net/eth0/duplex":    "full\n",
+		"sys/class/net/lo/mtu":         "65536\n",
+		"sys/class/net/lo/operstate":   "unknown\n",
+	})
+	src := ProcSource{Root: root}
+
+	devs, err := src.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth := devs[1]
+	if len(devs) != 2 || eth.Name != "eth0" || eth.BytesRecv != 2000000 || eth.BytesSent != 500000 ||
+		eth.Errin != 4 || eth.Errout != 2 || eth.Dropin != 6 || eth.Dropout != 8 ||
+		eth.Fifoin != 1 || eth.Fifoout != 3 || eth.Multicast != 30 || eth.FrameErrs != 2 ||
+		eth.Collisions != 5 || eth.Carrier != 7 {
+		t.Errorf("devices = %+v", devs)
+	}
+	if agg, err := src.IOCounters(false); err != nil || len(agg) != 1 || agg[0].Name != AllInterfaces ||
+		agg[0].BytesRecv != 2001000 || agg[0].Dropout != 8 {
+		t.Errorf("aggregate = %+v, %v", agg, err)
+	}
+	if _, err := ParseNetDev(strings.NewReader(netDevHeader + "eth0: 1 2 3\n")); err == nil {
+		t.Error("short line parsed")
+	}
+
+	infos, err := src.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []InterfaceInfo{
+		{Name: "eth0", Speed_bps: 1000 * Mb, MTU: 1500, OperState: "up", Duplex: "full"},
+		{Name: "lo", MTU: 65536, OperState: "unknown"},
+	}
+	if fmt.Sprint(infos) != fmt.Sprint(want) || !infos[0].Up() || infos[1].Up() {
+		t.Errorf("interfaces = %+v, want %+v", infos, want)
+	}
+	if _, err := src.Interface("../eth0"); err == nil {
+		t.Error("path outside sys/class/net accepted")
+	}
+
+	// the link speed seeds the data rate, loopback has none
+	params, err := NewLinkServiceParams(infos[0], DefaultLinkDistance, 1500*Byte, DefaultPackets)
+	if err != nil || params.DataRate_bps != 1000*Mb || params.ServiceRate_pps != 1000*Mb/(1500*Byte) || params.Iface != "eth0" {
+		t.Errorf("link params = %v, %v", params, err)
+	}
+	if _, err := NewLinkServiceParams(infos[1], DefaultLinkDistance, 1500*Byte, DefaultPackets); err == nil {
+		t.Error("loopback seeded a data rate")
+	}
+
+	// errors and drops between two readings become rates
+	writeFiles(t, root, map[string]string{
+		"proc/net/dev": netDevHeader +
+			"    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0\n" +
+			"  eth0: 3000000    3000   10   16    1     2          0        30   700000    1200    4   10    3     5       7          0\n",
+	})
+	next, err := src.IOCounters(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	fr := newDeltaFrame("eth0", eth.IOCountersStat, next[1], start, start.Add(2*time.Second))
+	if fr.ErrIn_pkt != 6 || fr.ErrOut_pkt != 2 || fr.DropIn_pkt != 10 || fr.DropOut_pkt != 2 ||
+		fr.Errs_pps != 4 || fr.Drops_pps != 6 {
+		t.Errorf("frame = %s", fr)
+	}
+}
+
+// nics builds counters for a mix of physical, loopback and virtual interfaces,
+// every interface sends n bytes in n/100 packets
+func nics(n uint64) []net.IOCountersStat {
+	var stats []net.IOCountersStat
+	for _, name := range []string{"eth0", "eth1", "enp3s0", "wlan0", "lo", "veth123", "docker0"} {
+		stats = append(stats, net.IOCountersStat{Name: name, BytesSent: n, PacketsSent: n / 100})
+	}
+	return stats
+}
+
+func TestNICFrames(t *testing.T) {
+	tests := []struct {
+		ifaces   []string
+		loopback bool
+		virtual  bool
+		want     []string
+	}{
+		{want: []string{"enp3s0", "eth0", "eth1", "wlan0"}},
+		{loopback: true, virtual: true, want: []string{"docker0", "enp3s0", "eth0", "eth1", "lo", "veth123", "wlan0"}},
+		{ifaces: []string{"eth"}, want: nil},
+		{ifaces: []string{"eth0"}, want: []string{"eth0"}},
+		{ifaces: []string{"*eth*"}, want: []string{"eth0", "eth1"}},
+		{ifaces: []string{"*eth*"}, virtual: true, want: []string{"eth0", "eth1", "veth123"}},
+		{ifaces: []string{"~^(en|wl)"}, want: []string{"enp3s0", "wlan0"}},
+		{ifaces: []string{"lo", "docker*"}, want: []string{"lo"}},
+	}
+	for _, tt := range tests {
+		clock := NewFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+		frames, err := NICFrames(NICOptions{
+			Interval: 2 * time.Second,
+			Ifaces:   tt.ifaces,
+			Loopback: tt.loopback,
+			Virtual:  tt.virtual,
+			Source:   NewReplaySource(nics(1000), nics(3000)),
+			Clock:    clock,
+		})
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%v: frames %v, want no match", tt.ifaces, frames)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.ifaces, err)
+			continue
+		}
+		got := make([]string, 0, len(frames))
+		for name, fr := range frames {
+			got = append(got, name)
+			if fr.Source != name || fr.Duration_s != 2 || fr.Upload_bps != 2000*8/2 || fr.PktsUp_pps != 10 {
+				t.Errorf("%v: frame %s", tt.ifaces, fr)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: interfaces %v, want %v", tt.ifaces, got, tt.want)
+		}
+	}
+	if _, err := NICFrames(NICOptions{Ifaces: []string{"~("}, Source: NewReplaySource()}); err == nil {
+		t.Error("invalid regex accepted")
+	}
+
+	// a sampler picks the same interfaces from the same list
+	for _, tt := range tests {
+		if tt.want == nil {
+			continue
+		}
+		var got []string
+		s := NewSampler(SamplerOptions{
+			PerNIC:   true,
+			Ifaces:   tt.ifaces,
+			Loopback: tt.loopback,
+			Virtual:  tt.virtual,
+			Source:   NewReplaySource(nics(1000), nics(3000)),
+			Clock:    NewFakeClock(time.Now()),
+			OnFrame:  func(fr *Frame) { got = append(got, fr.Source) },
+		})
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatalf("%v: %v", tt.ifaces, err)
+		}
+		<-s.Done()
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: sampler interfaces %v, want %v", tt.ifaces, got, tt.want)
+		}
+	}
+	if err := NewSampler(SamplerOptions{Ifaces: []string{"~("}, Source: NewReplaySource()}).Start(context.Background()); err == nil {
+		t.Error("sampler accepted an invalid regex")
+	}
+
+	// FilterIOCounters matches whole names without zero value padding
+	useReplay(t, nics(1000), nics(1000), nics(1000), nics(3000))
+	if stats := FilterIOCounters(true, "eth"); len(stats) != 0 {
+		t.Errorf("eth matched %v", stats)
+	}
+	if stats := FilterIOCounters(true, "eth0", "veth*"); len(stats) != 2 || stats[0].Name != "eth0" || stats[1].Name != "veth123" {
+		t.Errorf("eth0, veth* matched %v", stats)
+	}
+
+	// PopulateFrame measures the interfaces src selects
+	fr := NewFrame("eth*", 1, 2)
+	if fr.Source != "eth*" || fr.Sent_b != 2*2000*8 || fr.Sent_pkt != 40 {
+		t.Errorf("eth* frame %s", fr)
 	}
 }
@@ -2,7 +2,6 @@ package networks
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/danmuck/dps_lib/logs"
@@ -88,6 +87,13 @@ type Frame struct {
 	PktsUp_pps   float64 `json:"pkts_up"`      // packet rate in packets per second (mu)
 	PktsDown_pps float64 `json:"pkts_down"`    // packets received per second (lamda)
 	AvgPktSize   float64 `json:"avg_pkt_size"` // average packet size in bits
+
+	ErrIn_pkt   uint64  `json:"errs_in"`   // receive errors
+	ErrOut_pkt  uint64  `json:"errs_out"`  // send errors
+	DropIn_pkt  uint64  `json:"drops_in"`  // received packets dropped
+	DropOut_pkt uint64  `json:"drops_out"` // outgoing packets dropped
+	Errs_pps    float64 `json:"errs_pps"`  // errors per second in both directions
+	Drops_pps   float64 `json:"drops_pps"` // drops per second in both directions
 }
 
 func (fr *Frame) String() string {
@@ -106,18 +112,27 @@ func (fr *Frame) String() string {
 		Upload_bps: %f,
 		Download_bps: %f,
 		PktRate_pps: %f,
-		AvgPktSize: %f
+		AvgPktSize: %f,
+
+		ErrIn_pkt: %d,
+		ErrOut_pkt: %d,
+		DropIn_pkt: %d,
+		DropOut_pkt: %d,
+		Errs_pps: %f,
+		Drops_pps: %f
 	}`,
 		fr.Source, fr.Samples, fr.Timestamp, fr.Duration_s,
 		fr.Sent_b, fr.Recv_b, fr.Sent_pkt, fr.Recv_pkt,
-		fr.Upload_bps, fr.Download_bps, fr.PktsUp_pps, fr.AvgPktSize)
+		fr.Upload_bps, fr.Download_bps, fr.PktsUp_pps, fr.AvgPktSize,
+		fr.ErrIn_pkt, fr.ErrOut_pkt, fr.DropIn_pkt, fr.DropOut_pkt, fr.Errs_pps, fr.Drops_pps)
 }
 
-// filter networkio counters to specific interfaces
-// pernic of true with no ifaces returns all interfaces
+// filter networkio counters to specific interfaces, given as exact names, globs
+// like "eth*" or regexes like "~^en". pernic of true with no ifaces returns all
+// interfaces
 func FilterIOCounters(pernic bool, ifaces ...string) []net.IOCountersStat {
 	// get counters with given pernic
-	stats, err := net.IOCounters(pernic)
+	stats, err := currentSource().IOCounters(pernic)
 	if err != nil {
 		logs.Err("unable to read IO counters: %v", err)
 		return nil
@@ -128,12 +143,15 @@ func FilterIOCounters(pernic bool, ifaces ...string) []net.IOCountersStat {
 			logs.Err("no interfaces specified")
 			return stats
 		}
-		filtered := make([]net.IOCountersStat, len(ifaces))
+		sel, err := newIfaceSelector(ifaces, true, true)
+		if err != nil {
+			logs.Err("unable to filter IO counters: %v", err)
+			return nil
+		}
+		filtered := make([]net.IOCountersStat, 0, len(ifaces))
 		for _, stat := range stats {
-			for _, filters := range ifaces {
-				if strings.Contains(stat.Name, filters) {
-					filtered = append(filtered, stat)
-				}
+			if sel.match(stat.Name) {
+				filtered = append(filtered, stat)
 			}
 		}
 		logs.Debug("filtered %d interfaces", len(filtered))
@@ -142,45 +160,68 @@ func FilterIOCounters(pernic bool, ifaces ...string) []net.IOCountersStat {
 	return stats
 }
 
+// PopulateFrame measures the traffic of src over duration_s seconds on the
+// package CounterSource and Clock and fills fr with it. src is AllInterfaces,
+// or "", for every interface, otherwise the interfaces it selects as in
+// FilterIOCounters are summed up.
 func (fr *Frame) PopulateFrame(src string, samples, duration_s float64) {
+	clock := currentClock()
+	fr.Source = src
+	fr.Samples = samples
+	fr.Duration_s = duration_s
+	fr.Timestamp = clock.Now()
+
 	// initialize with single sample
-	sc := FilterIOCounters(false)
-	start := sc[0]
+	start, ok := readFrameSource(src)
+	if !ok {
+		return
+	}
 
-	time.Sleep(time.Second * time.Duration(duration_s))
+	sleep(clock, seconds(duration_s))
 
-	ec := FilterIOCounters(false)
-	end := ec[0]
+	end, ok := readFrameSource(src)
+	if !ok {
+		return
+	}
 
 	fr.ComputeDeltas(start, end)
 	fr.ComputeRates()
 	fr.ComputeAvgPktSize()
-
 }
 
-// new frame for overall traffic on all interfaces
-func NewFrame(src string, samples, duration_s float64) *Frame {
-	fr := &Frame{
-		Source:     src,
-		Samples:    samples,
-		Timestamp:  time.Now(),
-		Duration_s: duration_s,
+// readFrameSource reads the counters PopulateFrame measures for src
+func readFrameSource(src string) (net.IOCountersStat, bool) {
+	if src == "" || src == AllInterfaces {
+		stats := FilterIOCounters(false)
+		if len(stats) == 0 {
+			return net.IOCountersStat{}, false
+		}
+		return stats[0], true
 	}
-	// initialize with single sample
-	sc := FilterIOCounters(false)
-	start := sc[0]
-
-	time.Sleep(time.Second * time.Duration(duration_s))
-
-	ec := FilterIOCounters(false)
-	end := ec[0]
-
-	fr.ComputeDeltas(start, end)
-	fr.ComputeRates()
-	fr.ComputeAvgPktSize()
+	stats := FilterIOCounters(true, src)
+	if len(stats) == 0 {
+		logs.Warn("no interfaces match %q", src)
+		return net.IOCountersStat{}, false
+	}
+	total := net.IOCountersStat{Name: src}
+	for _, st := range stats {
+		total = addCounters(total, st)
+	}
+	return total, true
+}
 
+// new frame for the traffic of src, see PopulateFrame
+func NewFrame(src string, samples, duration_s float64) *Frame {
+	fr := &Frame{}
+	fr.PopulateFrame(src, samples, duration_s)
 	return fr
 }
+
+// seconds converts a float second count as used by Frame into a Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
 func NewSample() *Frame {
 	return nil
 }
@@ -190,6 +231,10 @@ func (fr *Frame) ComputeDeltas(start, next net.IOCountersStat) {
 	fr.Recv_b = float64(next.BytesRecv-start.BytesRecv) * 8
 	fr.Sent_pkt = next.PacketsSent - start.PacketsSent
 	fr.Recv_pkt = next.PacketsRecv - start.PacketsRecv
+	fr.ErrIn_pkt = next.Errin - start.Errin
+	fr.ErrOut_pkt = next.Errout - start.Errout
+	fr.DropIn_pkt = next.Dropin - start.Dropin
+	fr.DropOut_pkt = next.Dropout - start.Dropout
 	logs.Debug("%s, Bytes Sent: %s, Bytes Received: %s, Packets Sent: %d, Packets Received: %d",
 		fr.Timestamp.Format("15:04:05"),
 		FormatB(fr.Sent_b), FormatB(fr.Recv_b), fr.Sent_pkt, fr.Recv_pkt,
@@ -201,12 +246,20 @@ func (fr *Frame) ComputeRates() {
 	fr.Download_bps = fr.Recv_b / fr.Duration_s
 	fr.PktsUp_pps = float64(fr.Sent_pkt) / fr.Duration_s
 	fr.PktsDown_pps = float64(fr.Recv_pkt) / fr.Duration_s
+	fr.Errs_pps = float64(fr.ErrIn_pkt+fr.ErrOut_pkt) / fr.Duration_s
+	fr.Drops_pps = float64(fr.DropIn_pkt+fr.DropOut_pkt) / fr.Duration_s
 	logs.Debug("Upload: %s, Download: %s, Packets: %.2f p/s",
 		FormatBibi(fr.Upload_bps), FormatBibi(fr.Download_bps), fr.PktsUp_pps)
+	if fr.Errs_pps > 0 || fr.Drops_pps > 0 {
+		logs.Debug("Errors: %.2f p/s, Drops: %.2f p/s", fr.Errs_pps, fr.Drops_pps)
+	}
 }
 
 func (fr *Frame) ComputeAvgPktSize() {
-	fr.AvgPktSize = (fr.Sent_b + fr.Recv_b) / float64(fr.Sent_pkt+fr.Recv_pkt)
+	fr.AvgPktSize = 0 // no packets, no average
+	if pkts := fr.Sent_pkt + fr.Recv_pkt; pkts > 0 {
+		fr.AvgPktSize = (fr.Sent_b + fr.Recv_b) / float64(pkts)
+	}
 	logs.Debug("Average Packet Size: %s", FormatB(fr.AvgPktSize))
 }
 
@@ -246,6 +299,21 @@ func NewServiceParams(link_distance, data_rate, size float64, packets int, name
 		ServiceRate_pps: data_rate / size, // (μ) how fast you could serve them if no queueing debug:
 	}
 }
+
+// NewLinkServiceParams is NewServiceParams with the link speed of info as the
+// data rate, it fails when the interface reports none, e.g. loopback or a link
+// that is down:
+//
+//	info, err := networks.ReadInterfaceInfo("eth0")
+//	...
+//	params, err := networks.NewLinkServiceParams(info, 10_000, 1500*networks.Byte, 100)
+func NewLinkServiceParams(info InterfaceInfo, link_distance, size float64, packets int) (*ServiceParams, error) {
+	if info.Speed_bps <= 0 {
+		return nil, fmt.Errorf("networks: %s reports no link speed", info.Name)
+	}
+	return NewServiceParams(link_distance, info.Speed_bps, size, packets, info.Name), nil
+}
+
 func (s *ServiceParams) String() string {
 	return fmt.Sprintf(`
 	ServiceParams {
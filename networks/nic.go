@@ -0,0 +1,184 @@
+package networks
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/net"
+)
+
+// VirtualPrefixes are the name prefixes of interfaces treated as virtual:
+// container and vm links, bridges, tunnels and the like
+var VirtualPrefixes = []string{
+	"veth", "docker", "br-", "virbr", "vnet", "vmnet", "vboxnet", "tun", "tap",
+	"ifb", "dummy", "cni", "flannel", "cali", "vxlan", "kube", "utun", "awdl", "llw",
+}
+
+// IsLoopback reports whether name is a loopback interface such as lo or lo0
+func IsLoopback(name string) bool {
+	return strings.TrimRight(name, "0123456789") == "lo"
+}
+
+// IsVirtual reports whether name starts with one of the VirtualPrefixes
+func IsVirtual(name string) bool {
+	for _, prefix := range VirtualPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ifacePattern matches interface names exactly, as a glob like "eth*" or as a
+// regular expression when written with a leading "~", e.g. "~^en[ops]"
+type ifacePattern struct {
+	raw  string
+	glob bool
+	re   *regexp.Regexp
+}
+
+func parseIfacePattern(s string) (ifacePattern, error) {
+	p := ifacePattern{raw: s}
+	switch {
+	case strings.HasPrefix(s, "~"):
+		re, err := regexp.Compile(s[1:])
+		if err != nil {
+			return p, fmt.Errorf("networks: interface pattern %q: %w", s, err)
+		}
+		p.re = re
+	case strings.ContainsAny(s, `*?[\`):
+		if _, err := path.Match(s, ""); err != nil {
+			return p, fmt.Errorf("networks: interface pattern %q: %w", s, err)
+		}
+		p.glob = true
+	}
+	return p, nil
+}
+
+func (p ifacePattern) exact() bool {
+	return p.re == nil && !p.glob
+}
+
+func (p ifacePattern) match(name string) bool {
+	switch {
+	case p.re != nil:
+		return p.re.MatchString(name)
+	case p.glob:
+		ok, _ := path.Match(p.raw, name)
+		return ok
+	}
+	return p.raw == name
+}
+
+// ifaceSelector picks interfaces by patterns, every interface when there are
+// none. loopback and virtual interfaces are skipped unless kept or named exactly.
+type ifaceSelector struct {
+	patterns []ifacePattern
+	loopback bool
+	virtual  bool
+}
+
+func newIfaceSelector(patterns []string, loopback, virtual bool) (*ifaceSelector, error) {
+	sel := &ifaceSelector{loopback: loopback, virtual: virtual}
+	for _, raw := range patterns {
+		p, err := parseIfacePattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		sel.patterns = append(sel.patterns, p)
+	}
+	return sel, nil
+}
+
+func (s *ifaceSelector) match(name string) bool {
+	matched := len(s.patterns) == 0
+	for _, p := range s.patterns {
+		if !p.match(name) {
+			continue
+		}
+		if p.exact() {
+			return true
+		}
+		matched = true
+	}
+	if !matched {
+		return false
+	}
+	return (s.loopback || !IsLoopback(name)) && (s.virtual || !IsVirtual(name))
+}
+
+// NICOptions configures NICFrames
+type NICOptions struct {
+	Interval time.Duration // time between the two readings, one second when zero
+	Ifaces   []string      // exact names, globs like "eth*" or regexes like "~^en", every interface when empty
+	Loopback bool          // keep loopback interfaces matched by a glob or regex
+	Virtual  bool          // keep virtual interfaces matched by a glob or regex, see VirtualPrefixes
+	Source   CounterSource // the package source when nil, see SetCounterSource
+	Clock    Clock         // the package clock when nil, see SetClock
+}
+
+// NICFrames measures every selected interface over a single interval and
+// returns a frame per interface keyed by its name:
+//
+//	frames, err := networks.NICFrames(networks.NICOptions{Ifaces: []string{"eth*", "~^en"}})
+//
+// interfaces that appear, disappear or reset their counters during the
+// interval are left out. it fails if no interface matches.
+func NICFrames(opts NICOptions) (map[string]*Frame, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Source == nil {
+		opts.Source = currentSource()
+	}
+	if opts.Clock == nil {
+		opts.Clock = currentClock()
+	}
+	sel, err := newIfaceSelector(opts.Ifaces, opts.Loopback, opts.Virtual)
+	if err != nil {
+		return nil, err
+	}
+
+	start, from, err := readNICs(opts.Source, opts.Clock, sel)
+	if err != nil {
+		return nil, err
+	}
+	sleep(opts.Clock, opts.Interval)
+	end, to, err := readNICs(opts.Source, opts.Clock, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make(map[string]*Frame, len(end))
+	for name, st := range end {
+		prev, ok := start[name]
+		if !ok || counterReset(prev, st) {
+			continue
+		}
+		frames[name] = newDeltaFrame(name, prev, st, from, to)
+	}
+	return frames, nil
+}
+
+// readNICs reads the per interface counters selected by sel and when they were read
+func readNICs(src CounterSource, clock Clock, sel *ifaceSelector) (map[string]net.IOCountersStat, time.Time, error) {
+	stats, err := src.IOCounters(true)
+	now := clock.Now()
+	if err != nil {
+		return nil, now, err
+	}
+	out := make(map[string]net.IOCountersStat)
+	for _, st := range stats {
+		if sel.match(st.Name) {
+			out[st.Name] = st
+		}
+	}
+	if len(out) == 0 {
+		return nil, now, errors.New("networks: no matching interfaces")
+	}
+	return out, now, nil
+}
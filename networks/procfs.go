@@ -0,0 +1,192 @@
+package networks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/net"
+)
+
+// ProcSource reads the counters straight from /proc/net/dev and the link
+// metadata from /sys/class/net, so it only works on linux. Root prefixes both
+// paths, e.g. for a host mount inside a container or test fixtures:
+//
+//	prev := networks.SetCounterSource(networks.ProcSource{})
+//	defer networks.SetCounterSource(prev)
+type ProcSource struct {
+	Root string // "/" when empty
+}
+
+// DevStats are the counters of one interface in /proc/net/dev. the embedded
+// counters are the ones a CounterSource returns, the rest has no place there.
+type DevStats struct {
+	net.IOCountersStat
+	Multicast  uint64 `json:"multicast"` // multicast packets received
+	FrameErrs  uint64 `json:"frame"`     // receive framing errors
+	Collisions uint64 `json:"colls"`     // collisions while sending
+	Carrier    uint64 `json:"carrier"`   // carrier losses while sending
+}
+
+// InterfaceInfo is the link metadata of an interface from /sys/class/net
+type InterfaceInfo struct {
+	Name      string  `json:"interface"`
+	Speed_bps float64 `json:"speed_bps"` // link speed, 0 when unknown or down
+	MTU       int     `json:"mtu"`       // in bytes
+	OperState string  `json:"operstate"` // "up", "down", "unknown", ...
+	Duplex    string  `json:"duplex"`    // "full" or "half", "" when unknown
+}
+
+// Up reports whether the kernel considers the link operational
+func (i InterfaceInfo) Up() bool {
+	return i.OperState == "up"
+}
+
+func (p ProcSource) path(elem ...string) string {
+	root := p.Root
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+func (p ProcSource) IOCounters(pernic bool) ([]net.IOCountersStat, error) {
+	devs, err := p.Devices()
+	if err != nil {
+		return nil, err
+	}
+	if pernic {
+		stats := make([]net.IOCountersStat, 0, len(devs))
+		for _, d := range devs {
+			stats = append(stats, d.IOCountersStat)
+		}
+		return stats, nil
+	}
+	total := net.IOCountersStat{Name: AllInterfaces}
+	for _, d := range devs {
+		total = addCounters(total, d.IOCountersStat)
+	}
+	return []net.IOCountersStat{total}, nil
+}
+
+// Devices returns the full counters of every interface in /proc/net/dev
+func (p ProcSource) Devices() ([]DevStats, error) {
+	f, err := os.Open(p.path("proc", "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseNetDev(f)
+}
+
+// ParseNetDev parses the format of /proc/net/dev, the two header lines are skipped
+func ParseNetDev(r io.Reader) ([]DevStats, error) {
+	var devs []DevStats
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		name, counters, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue // header
+		}
+		fields := strings.Fields(counters)
+		if len(fields) != 16 {
+			return nil, fmt.Errorf("networks: net/dev line %d: %d counters, want 16", line, len(fields))
+		}
+		var v [16]uint64
+		for i, f := range fields {
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("networks: net/dev line %d: %w", line, err)
+			}
+			v[i] = n
+		}
+		// receive: bytes packets errs drop fifo frame compressed multicast
+		// transmit: bytes packets errs drop fifo colls carrier compressed
+		devs = append(devs, DevStats{
+			IOCountersStat: net.IOCountersStat{
+				Name:        strings.TrimSpace(name),
+				BytesRecv:   v[0],
+				PacketsRecv: v[1],
+				Errin:       v[2],
+				Dropin:      v[3],
+				Fifoin:      v[4],
+				BytesSent:   v[8],
+				PacketsSent: v[9],
+				Errout:      v[10],
+				Dropout:     v[11],
+				Fifoout:     v[12],
+			},
+			FrameErrs:  v[5],
+			Multicast:  v[7],
+			Collisions: v[13],
+			Carrier:    v[14],
+		})
+	}
+	return devs, sc.Err()
+}
+
+// Interface reads the link metadata of name. attributes the interface does not
+// support, like the speed of loopback, are left at their zero value.
+func (p ProcSource) Interface(name string) (InterfaceInfo, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return InterfaceInfo{}, fmt.Errorf("networks: invalid interface name %q", name)
+	}
+	dir := p.path("sys", "class", "net", name)
+	if _, err := os.Stat(dir); err != nil {
+		return InterfaceInfo{}, err
+	}
+	info := InterfaceInfo{Name: name}
+	attr := func(file string) string {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	// speed is in Mb/s and -1 while the link is down
+	if speed, err := strconv.ParseFloat(attr("speed"), 64); err == nil && speed > 0 {
+		info.Speed_bps = speed * Mb / sec
+	}
+	info.MTU, _ = strconv.Atoi(attr("mtu"))
+	info.OperState = attr("operstate")
+	if duplex := attr("duplex"); duplex != "unknown" {
+		info.Duplex = duplex
+	}
+	return info, nil
+}
+
+// Interfaces reads the link metadata of every interface sorted by name
+func (p ProcSource) Interfaces() ([]InterfaceInfo, error) {
+	entries, err := os.ReadDir(p.path("sys", "class", "net"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	infos := make([]InterfaceInfo, 0, len(names))
+	for _, name := range names {
+		info, err := p.Interface(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // dangling link of a removed interface
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ReadInterfaceInfo reads the link metadata of name from /sys/class/net
+func ReadInterfaceInfo(name string) (InterfaceInfo, error) {
+	return ProcSource{}.Interface(name)
+}
@@ -0,0 +1,265 @@
+package networks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/danmuck/dps_lib/logs"
+	"github.com/shirou/gopsutil/net"
+)
+
+// AllInterfaces is the Source of aggregate frames
+const AllInterfaces = "all"
+
+// DefaultMaxErrors is the number of failed reads in a row that stop a sampler
+// when SamplerOptions.MaxErrors is 0
+const DefaultMaxErrors = 5
+
+// SamplerOptions configures a Sampler
+type SamplerOptions struct {
+	Interval  time.Duration // time between frames, one second when zero
+	PerNIC    bool          // one frame per interface and tick instead of a single aggregate
+	Ifaces    []string      // exact names, globs like "eth*" or regexes like "~^en", all when empty
+	Loopback  bool          // keep loopback interfaces matched by a glob or regex, as in NICOptions
+	Virtual   bool          // keep virtual interfaces matched by a glob or regex, as in NICOptions
+	Buffer    int           // capacity of the Frames channel
+	MaxErrors int           // failed reads in a row before the sampler stops, DefaultMaxErrors when zero
+	OnFrame   func(*Frame)  // called for every frame instead of sending it on Frames
+	Source    CounterSource // the package source when nil, see SetCounterSource
+	Clock     Clock         // the package clock when nil, see SetClock
+}
+
+// Sampler reads the interface counters every interval and turns the
+// difference to the previous reading into a Frame:
+//
+//	s := networks.NewSampler(networks.SamplerOptions{Interval: time.Second, PerNIC: true})
+//	if err := s.Start(ctx); err != nil {
+//		return err
+//	}
+//	for fr := range s.Frames() {
+//		logs.Info("%s up %s/s", fr.Source, networks.FormatBibi(fr.Upload_bps))
+//	}
+//
+// cancelling ctx, a ReplaySource running out or MaxErrors failed reads in a
+// row stop the sampler and close Frames.
+type Sampler struct {
+	opts   SamplerOptions
+	frames chan *Frame
+	done   chan struct{}
+	once   sync.Once
+	sel    *ifaceSelector
+	selErr error // invalid Ifaces pattern, returned by Start
+	err    error // why the sampler gave up, set before done is closed
+}
+
+func NewSampler(opts SamplerOptions) *Sampler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Buffer < 0 {
+		opts.Buffer = 0
+	}
+	if opts.MaxErrors <= 0 {
+		opts.MaxErrors = DefaultMaxErrors
+	}
+	if opts.Source == nil {
+		opts.Source = currentSource()
+	}
+	if opts.Clock == nil {
+		opts.Clock = currentClock()
+	}
+	sel, err := newIfaceSelector(opts.Ifaces, opts.Loopback, opts.Virtual)
+	return &Sampler{
+		opts:   opts,
+		frames: make(chan *Frame, opts.Buffer),
+		done:   make(chan struct{}),
+		sel:    sel,
+		selErr: err,
+	}
+}
+
+// Start takes the first reading and samples on its own goroutine until ctx is
+// done. it fails if an Ifaces pattern is invalid, the counters cannot be read
+// or the sampler already ran.
+func (s *Sampler) Start(ctx context.Context) error {
+	err := errors.New("networks: sampler already started")
+	s.once.Do(func() {
+		var prev map[string]net.IOCountersStat
+		err = s.selErr
+		if err == nil {
+			prev, err = s.read()
+		}
+		if err != nil {
+			close(s.frames)
+			close(s.done)
+			return
+		}
+		go s.run(ctx, prev)
+	})
+	return err
+}
+
+// Frames delivers a frame per interface, or one aggregate, every interval.
+// it is closed once the sampler stops and unused when OnFrame is set.
+func (s *Sampler) Frames() <-chan *Frame {
+	return s.frames
+}
+
+// Done is closed after the sampler stopped and delivered its last frame
+func (s *Sampler) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the read error the sampler stopped on, nil when ctx or the end
+// of a replay stopped it. it is only set once Done is closed.
+func (s *Sampler) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *Sampler) run(ctx context.Context, prev map[string]net.IOCountersStat) {
+	defer close(s.done)
+	defer close(s.frames)
+	last := s.opts.Clock.Now()
+	failures := 0
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-s.opts.Clock.After(s.opts.Interval):
+		}
+		curr, err := s.read()
+		if errors.Is(err, ErrReplayDone) {
+			return
+		}
+		if err != nil {
+			if failures++; failures >= s.opts.MaxErrors {
+				s.err = fmt.Errorf("networks: sampler stopped after %d failed reads: %w", failures, err)
+				logs.Err("%v", s.err)
+				return
+			}
+			logs.Warn("sampler: %v", err)
+			continue
+		}
+		failures = 0
+		for _, name := range sortedKeys(curr) {
+			start, ok := prev[name]
+			if !ok || counterReset(start, curr[name]) {
+				continue // new interface or reset counters, wait for the next tick
+			}
+			if !s.deliver(ctx, newDeltaFrame(name, start, curr[name], last, now)) {
+				return
+			}
+		}
+		prev, last = curr, now
+	}
+}
+
+// deliver hands fr to OnFrame or the channel, it reports false once ctx is done
+func (s *Sampler) deliver(ctx context.Context, fr *Frame) bool {
+	if s.opts.OnFrame != nil {
+		s.opts.OnFrame(fr)
+		return ctx.Err() == nil
+	}
+	select {
+	case s.frames <- fr:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// read returns the counters to sample keyed by interface, or by AllInterfaces
+// in aggregate mode. interfaces are picked like NICFrames does, only the
+// aggregate of every interface is read as a whole.
+func (s *Sampler) read() (map[string]net.IOCountersStat, error) {
+	if !s.opts.PerNIC && len(s.opts.Ifaces) == 0 {
+		stats, err := s.opts.Source.IOCounters(false)
+		if err != nil {
+			return nil, err
+		}
+		if len(stats) == 0 {
+			return nil, errors.New("networks: no IO counters")
+		}
+		return map[string]net.IOCountersStat{AllInterfaces: stats[0]}, nil
+	}
+	stats, err := s.opts.Source.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]net.IOCountersStat)
+	var total net.IOCountersStat
+	for _, st := range stats {
+		if !s.sel.match(st.Name) {
+			continue
+		}
+		if s.opts.PerNIC {
+			out[st.Name] = st
+			continue
+		}
+		total = addCounters(total, st)
+	}
+	if !s.opts.PerNIC {
+		total.Name = AllInterfaces
+		out[AllInterfaces] = total
+	}
+	if len(out) == 0 {
+		return nil, errors.New("networks: no matching interfaces")
+	}
+	return out, nil
+}
+
+// newDeltaFrame builds a frame from two readings of the same counters
+func newDeltaFrame(src string, start, end net.IOCountersStat, from, to time.Time) *Frame {
+	fr := &Frame{
+		Source:     src,
+		Samples:    1,
+		Timestamp:  from,
+		Duration_s: to.Sub(from).Seconds(),
+	}
+	fr.ComputeDeltas(start, end)
+	fr.ComputeRates()
+	fr.ComputeAvgPktSize()
+	return fr
+}
+
+// counterReset reports whether any counter went backwards, e.g. after the
+// interface was recreated
+func counterReset(start, end net.IOCountersStat) bool {
+	return end.BytesSent < start.BytesSent || end.BytesRecv < start.BytesRecv ||
+		end.PacketsSent < start.PacketsSent || end.PacketsRecv < start.PacketsRecv ||
+		end.Errin < start.Errin || end.Errout < start.Errout ||
+		end.Dropin < start.Dropin || end.Dropout < start.Dropout
+}
+
+func addCounters(a, b net.IOCountersStat) net.IOCountersStat {
+	a.BytesSent += b.BytesSent
+	a.BytesRecv += b.BytesRecv
+	a.PacketsSent += b.PacketsSent
+	a.PacketsRecv += b.PacketsRecv
+	a.Errin += b.Errin
+	a.Errout += b.Errout
+	a.Dropin += b.Dropin
+	a.Dropout += b.Dropout
+	a.Fifoin += b.Fifoin
+	a.Fifoout += b.Fifoout
+	return a
+}
+
+func sortedKeys(m map[string]net.IOCountersStat) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
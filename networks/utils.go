@@ -1,13 +1,13 @@
 package networks
 
 import (
+	"context"
 	"math"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/danmuck/dps_lib/logs"
-	"github.com/shirou/gopsutil/net"
 )
 
 // default formatting constants helper
@@ -93,80 +93,61 @@ func formatFloat(v float64, decDigits int) string {
 	return s
 }
 
-// note: rmm post testing
-// refactored out
+// GenerateFrame samples the aggregate traffic of all interfaces once a second
+// for duration_s seconds, logging the rates of every second, and returns the
+// traffic of the whole period as a single Frame. durations above a second are
+// rounded up to whole seconds, so 2.5 takes three samples. it returns nil if
+// the counters cannot be read and the samples taken so far when reads keep
+// failing. distance_m is unused, link distance belongs to ServiceParams.
 func GenerateFrame(distance_m, duration_s float64) *Frame {
-	ival := 1 * time.Second
-	// 1. Get the initial cumulative counters (aggregate over all interfaces)
-	bootstrap := FilterIOCounters(false)
+	duration := time.Duration(duration_s * float64(time.Second))
+	ival := time.Second
+	if duration < ival {
+		ival = duration
+	}
+	ticks := 1
+	if ival > 0 {
+		ticks = max(1, int(math.Ceil(float64(duration)/float64(ival))))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	fr := &Frame{
-		Source:     "interface or all",
-		Duration_s: duration_s,
-		Payload:    make([]byte, int(256/8)), // convert bits to bytes note: 256 is filler!!
-		Timestamp:  time.Now(),
-		Samples:    0,
-
-		Sent_b:   0,
-		Recv_b:   0,
-		Sent_pkt: 0,
-		Recv_pkt: 0,
-
-		Upload_bps:   0,
-		Download_bps: 0,
-		PktsUp_pps:   0,
+		Source:    AllInterfaces,
+		Payload:   make([]byte, int(256/8)), // convert bits to bytes note: 256 is filler!!
+		Timestamp: currentClock().Now(),
 	}
-
-	// use index 0 because pernic=false returns a single aggregate counter
-	prev := bootstrap[0]
-	ticker := time.NewTicker(ival)
-	defer ticker.Stop()
-	for now := range ticker.C {
-
-		// 2. Read them again
-		counters, err := net.IOCounters(false)
-		if err != nil {
-			logs.Warn("error reading IO counters: %v", err)
-			continue
+	sampler := NewSampler(SamplerOptions{Interval: ival})
+	if err := sampler.Start(ctx); err != nil {
+		logs.Warn("error reading IO counters: %v", err)
+		return nil
+	}
+	for tick := range sampler.Frames() {
+		logs.Info("Upload: %.2f bps, Download: %.2f bps", tick.Upload_bps, tick.Download_bps)
+		logs.Log("%s → Upload: %s/s, Download: %s/s",
+			tick.Timestamp.Format("15:04:05"), FormatBibi(tick.Upload_bps), FormatBibi(tick.Download_bps))
+
+		fr.Samples++
+		fr.Duration_s += tick.Duration_s
+		fr.Sent_b += tick.Sent_b
+		fr.Recv_b += tick.Recv_b
+		fr.Sent_pkt += tick.Sent_pkt
+		fr.Recv_pkt += tick.Recv_pkt
+		fr.ErrIn_pkt += tick.ErrIn_pkt
+		fr.ErrOut_pkt += tick.ErrOut_pkt
+		fr.DropIn_pkt += tick.DropIn_pkt
+		fr.DropOut_pkt += tick.DropOut_pkt
+		if int(fr.Samples) >= ticks {
+			break
 		}
-		curr := counters[0]
-
-		// 3. Calculate deltas
-		frSent := float64(curr.BytesSent - prev.BytesSent)
-		frRecv := float64(curr.BytesRecv - prev.BytesRecv)
-		frSentPkts := float64(curr.PacketsSent - prev.PacketsSent)
-		frRecvPkts := float64(curr.PacketsRecv - prev.PacketsRecv)
-
-		logs.Dev("%s, Bytes Sent: %.2f, Bytes Received: %.2f, Packets Sent: %.2f, Packets Received: %.2f",
-			now.Format("15:04:05"), frSent, frRecv,
-			frSentPkts, frRecvPkts)
-		// 4. Compute rates (bytes per second)
-		//    delta bytes / ticker seconds == bytes/sec
-		upload_Bps := frSent / ival.Seconds()
-		download_Bps := frRecv / ival.Seconds()
-		packets_ps := frSentPkts + frRecvPkts/ival.Seconds()
-		avgPacketSize_B := (frSent + frRecv) / (frSentPkts + frRecvPkts)
-
-		logs.Warn("Upload: %.2f B/s, Download: %.2f B/s, Packets/s: %.2f, Avg Packet Size: %.2f B",
-			upload_Bps, download_Bps, packets_ps, avgPacketSize_B)
-
-		// conver to bits per second
-		upload_bps := upload_Bps * 8 // bits per second
-		download_bps := download_Bps * 8
-		logs.Info("Upload: %.2f bps, Download: %.2f bps", upload_bps, download_bps)
-
-		// 5. (Optional) convert to more human units, e.g. KiB/s
-		uploadKiB := upload_Bps / 1024
-		downloadKiB := download_Bps / 1024
-		logs.Info("Upload: %s/s, Download: %s/s",
-			FormatBibi(upload_bps), FormatBibi(download_bps))
-		// 6. Print
-
-		logs.Log("%s → Upload: %.2f KiB/s, Download: %.2f KiB/s\n",
-			now.Format("15:04:05"), uploadKiB, downloadKiB)
-
-		// 7. Prepare for next iteration
-		prev = curr
 	}
+	if err := sampler.Err(); err != nil {
+		logs.Warn("frame cut short after %.0f samples: %v", fr.Samples, err)
+	}
+	if fr.Samples == 0 {
+		return nil
+	}
+	fr.ComputeRates()
+	fr.ComputeAvgPktSize()
 	return fr
 }